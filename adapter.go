@@ -0,0 +1,169 @@
+package orchestra
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// closer is the player returned by `orchestra.FromCloser`
+type closer struct {
+	c      io.Closer
+	closed bool
+}
+
+// FromCloser adapts something that starts working as soon as it's created, and stops when it's closed, into a player.
+// Its Play blocks until the context is cancelled, and then closes c, returning the error returned by Close.
+// If the player is cleaned without having played, c is closed in Clean instead.
+func FromCloser(c io.Closer) Player {
+	return &closer{c: c}
+}
+
+func (c *closer) Setup() error {
+	return nil
+}
+
+func (c *closer) Play(ctx context.Context) error {
+	<-ctx.Done()
+	c.closed = true
+	return c.c.Close()
+}
+
+func (c *closer) Clean() {
+	if !c.closed {
+		c.c.Close()
+	}
+}
+
+// startStop is the player returned by `orchestra.FromStartStop`
+type startStop struct {
+	start, stop func() error
+}
+
+// FromStartStop adapts something with a Start/Stop (or Start/Close) style of life cycle into a player, for eg, `orchestra.FromStartStop(w.Start, w.Close)`.
+// Its Play calls start, blocks until the context is cancelled, and then calls stop.
+// start is expected to return as soon as the work has started, and not block till it's done.
+// If start fails, stop isn't called, and the error is returned from Play.
+func FromStartStop(start, stop func() error) Player {
+	return &startStop{start: start, stop: stop}
+}
+
+func (s *startStop) Setup() error {
+	return nil
+}
+
+func (s *startStop) Play(ctx context.Context) error {
+	if err := s.start(); err != nil {
+		return err
+	}
+	<-ctx.Done()
+	return s.stop()
+}
+
+func (s *startStop) Clean() {}
+
+// Shutdowner is anything that can be shutdown gracefully within a deadline, like *http.Server
+type Shutdowner interface {
+	Shutdown(context.Context) error
+}
+
+// shutdowner is the player returned by `orchestra.FromShutdowner`
+type shutdowner struct {
+	sd    Shutdowner
+	serve func() error
+	grace time.Duration
+}
+
+// FromShutdowner adapts anything that implements `orchestra.Shutdowner` into a player, for eg,
+//
+//	orchestra.FromShutdowner(srv, srv.ListenAndServe, 10*time.Second)
+//
+// Its Play calls serve (if it's non-nil) in a goroutine, and blocks until the context is cancelled, or serve returns.
+// On cancellation, Shutdown is called with a context that expires after grace, and if it doesn't complete in time,
+// sd is closed as a fallback if it implements io.Closer.
+// Errors returned by serve after the shutdown has begun (like http.ErrServerClosed) are ignored, the error from Shutdown is returned instead.
+func FromShutdowner(sd Shutdowner, serve func() error, grace time.Duration) Player {
+	return &shutdowner{sd: sd, serve: serve, grace: grace}
+}
+
+func (s *shutdowner) Setup() error {
+	return nil
+}
+
+func (s *shutdowner) Play(ctx context.Context) error {
+	var served chan error // stays nil, and never fires, if there's nothing to serve
+	if s.serve != nil {
+		served = make(chan error, 1)
+		go func() {
+			served <- s.serve()
+		}()
+	}
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+
+	gctx, cancel := context.WithTimeout(context.Background(), s.grace)
+	defer cancel()
+	err := s.sd.Shutdown(gctx)
+	if gctx.Err() != nil {
+		c, ok := s.sd.(io.Closer)
+		if !ok {
+			return err // there's no way to force it, so serve may never return
+		}
+		c.Close()
+	}
+	if served != nil {
+		<-served
+	}
+	return err
+}
+
+func (s *shutdowner) Clean() {}
+
+// stopChan is the player returned by `orchestra.FromStopChan`
+type stopChan func(stop <-chan struct{}) error
+
+// FromStopChan adapts a worker of the pre-context era, that runs until a stop channel is closed, into a player, for eg, `orchestra.FromStopChan(w.Run)`.
+// Its Play calls run in a goroutine, closes the stop channel when the context is cancelled, and waits for run to return.
+func FromStopChan(run func(stop <-chan struct{}) error) Player {
+	return stopChan(run)
+}
+
+func (run stopChan) Setup() error {
+	return nil
+}
+
+func (run stopChan) Play(ctx context.Context) error {
+	stop := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- run(stop)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+	close(stop)
+	return <-done
+}
+
+func (run stopChan) Clean() {}
+
+// Legacy is a worker of the pre-context era, that starts working on Start, and stops on Stop
+type Legacy interface {
+	Start()
+	Stop() // expected to block till the worker has stopped
+}
+
+// FromLegacy adapts a `orchestra.Legacy` worker into a player.
+// Its Play calls Start, blocks until the context is cancelled, and then calls Stop.
+// Workers whose Start or Stop return errors can use `orchestra.FromStartStop` instead.
+func FromLegacy(w Legacy) Player {
+	return &startStop{
+		start: func() error { w.Start(); return nil },
+		stop:  func() error { w.Stop(); return nil },
+	}
+}
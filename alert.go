@@ -0,0 +1,80 @@
+package orchestra
+
+import (
+	"sync"
+	"time"
+)
+
+// Threshold describes a rate of failures that should raise an alert, see `orchestra.AlertOn`
+type Threshold struct {
+	Failures int           // the number of failures that raise the alert
+	Within   time.Duration // the duration they have to happen within
+	Players  []string      // the names of the players being watched, every player is watched if it's empty
+	Notify   func(Alert)   // called when the threshold is crossed
+}
+
+// Alert is raised when the failures of the players cross a `orchestra.Threshold`
+type Alert struct {
+	At        time.Time
+	Threshold Threshold
+	Failures  map[string]int // the number of failures of each player within the threshold's duration
+}
+
+// AlertOn makes the stage notify when its players fail more than the threshold allows, for eg, more than 5 times in 10 minutes.
+// Every error counts, including the ones absorbed by restarts, so degradation can be noticed even if the players recover on their own.
+// The alert is raised once when the threshold is crossed, and only raised again after the failures have dropped below it.
+// Notify is called from the goroutine of the player that failed last.
+func AlertOn(t Threshold) StageOption {
+	return func(s *Stage) {
+		a := &alarm{threshold: t}
+		if len(t.Players) > 0 {
+			a.watched = make(map[string]bool, len(t.Players))
+			for _, name := range t.Players {
+				a.watched[name] = true
+			}
+		}
+		s.alarms = append(s.alarms, a)
+	}
+}
+
+// alarm keeps track of the failures for a threshold
+type alarm struct {
+	threshold Threshold
+	watched   map[string]bool // nil if every player is watched
+
+	mu       sync.Mutex
+	failures []record // the failures within the threshold's duration, oldest first
+	raised   bool
+}
+
+// fail records a failure, and raises the alert if the threshold is crossed
+func (a *alarm) fail(r record) {
+	if a.watched != nil && !a.watched[r.player] {
+		return
+	}
+	a.mu.Lock()
+	cutoff := r.at.Add(-a.threshold.Within)
+	i := 0
+	for i < len(a.failures) && !a.failures[i].at.After(cutoff) {
+		i++
+	}
+	a.failures = append(a.failures[i:], r)
+	if len(a.failures) < a.threshold.Failures {
+		a.raised = false
+		a.mu.Unlock()
+		return
+	}
+	if a.raised {
+		a.mu.Unlock()
+		return
+	}
+	a.raised = true
+	alert := Alert{At: r.at, Threshold: a.threshold, Failures: make(map[string]int)}
+	for _, f := range a.failures {
+		alert.Failures[f.player]++
+	}
+	a.mu.Unlock()
+	if a.threshold.Notify != nil {
+		a.threshold.Notify(alert)
+	}
+}
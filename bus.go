@@ -0,0 +1,129 @@
+package orchestra
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Overflow is what happens to an event when the buffer of a subscriber is full, see (*Stage).Subscribe
+type Overflow struct {
+	policy  policy
+	timeout time.Duration // how long to block for, before dropping the new event
+}
+
+type policy int
+
+const (
+	dropNewest policy = iota
+	dropOldest
+	block
+)
+
+var (
+	DropNewest = Overflow{policy: dropNewest} // the new event is dropped
+	DropOldest = Overflow{policy: dropOldest} // the oldest buffered event is dropped, to make room for the new one
+)
+
+// BlockFor blocks the player that caused the event for up to d, waiting for the subscriber to make room, and drops the event if it doesn't.
+// Note: it slows the players of the stage down to the pace of the subscriber, so d should be small
+func BlockFor(d time.Duration) Overflow {
+	return Overflow{policy: block, timeout: d}
+}
+
+// Subscription is a subscription to the life cycle events of the players on a stage, see (*Stage).Subscribe
+type Subscription struct {
+	C <-chan Event // the events, it is closed once the subscription is closed
+
+	mu       sync.Mutex // serializes the deliveries, and guards closed
+	ch       chan Event
+	overflow Overflow
+	dropped  atomic.Uint64
+	closed   bool
+	stage    *Stage
+}
+
+// Subscribe subscribes to every life cycle event of the players on the stage, see `orchestra.Event`, with a buffer of the given size.
+// When the buffer is full, the events are handled as per the overflow policy, so a slow subscriber (like a dashboard) can't stall the stage.
+// The events of the players on nested stages aren't included, subscribe to the nested stages to get them.
+func (s *Stage) Subscribe(buffer int, overflow Overflow) *Subscription {
+	ch := make(chan Event, buffer)
+	sub := &Subscription{C: ch, ch: ch, overflow: overflow, stage: s}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subscribers = append(s.subscribers, sub)
+	return sub
+}
+
+// Dropped returns the number of events that were dropped because the buffer of the subscription was full
+func (sub *Subscription) Dropped() uint64 {
+	return sub.dropped.Load()
+}
+
+// Close unsubscribes, and closes the channel of the subscription. Closing it more than once does nothing
+func (sub *Subscription) Close() {
+	s := sub.stage
+	s.mu.Lock()
+	for i, other := range s.subscribers {
+		if other == sub {
+			s.subscribers = append(s.subscribers[:i:i], s.subscribers[i+1:]...)
+			break
+		}
+	}
+	s.mu.Unlock()
+
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if !sub.closed {
+		sub.closed = true
+		close(sub.ch)
+	}
+}
+
+// deliver sends the event to the subscriber, as per its overflow policy
+func (sub *Subscription) deliver(e Event) {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if sub.closed {
+		return
+	}
+	select {
+	case sub.ch <- e:
+		return
+	default:
+	}
+	switch sub.overflow.policy {
+	case dropOldest:
+		for {
+			select {
+			case sub.ch <- e:
+				return
+			default:
+			}
+			select {
+			case <-sub.ch:
+				sub.dropped.Add(1)
+			default: // the subscriber got to it first
+			}
+		}
+	case block:
+		t := time.NewTimer(sub.overflow.timeout)
+		defer t.Stop()
+		select {
+		case sub.ch <- e:
+			return
+		case <-t.C:
+		}
+	}
+	sub.dropped.Add(1)
+}
+
+// publish delivers the event to every subscriber
+func (s *Stage) publish(e Event) {
+	s.mu.Lock()
+	subs := s.subscribers
+	s.mu.Unlock()
+	for _, sub := range subs {
+		sub.deliver(e)
+	}
+}
@@ -0,0 +1,127 @@
+package orchestra
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// ErrChaos is the error injected by `orchestra.Chaos`
+type ErrChaos struct {
+	Player string
+}
+
+func (e ErrChaos) Error() string {
+	return fmt.Sprintf("ErrChaos: %s: injected failure", e.Player)
+}
+
+// Chaos randomly injects failures into players, to verify that the application handles them:
+// delayed Setups, spurious errors from Play, early cancellation of Play, and slow Cleans.
+// The same seed produces the same decisions (timing aside), so failures can be reproduced.
+//
+//	stage.Wrap(orchestra.NewChaos(42, 0.1, time.Second).Wrap)
+type Chaos struct {
+	mu          sync.Mutex
+	rand        *rand.Rand
+	probability float64
+	maxDelay    time.Duration
+}
+
+// NewChaos creates a chaos monkey, where probability (0 to 1) is the chance of a failure at every opportunity,
+// and maxDelay bounds the delays it injects
+func NewChaos(seed int64, probability float64, maxDelay time.Duration) *Chaos {
+	return &Chaos{
+		rand:        rand.New(rand.NewSource(seed)),
+		probability: probability,
+		maxDelay:    maxDelay,
+	}
+}
+
+// Wrap returns a player that behaves like p, except for the failures injected into it, it's meant to be passed to (*Stage).Wrap
+func (c *Chaos) Wrap(name string, p Player) Player {
+	return &chaotic{name: name, player: p, chaos: c}
+}
+
+// strike reports whether a failure should be injected, and how long it should be delayed
+func (c *Chaos) strike() (bool, time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.rand.Float64() >= c.probability {
+		return false, 0
+	}
+	if c.maxDelay <= 0 {
+		return true, 0
+	}
+	return true, time.Duration(c.rand.Int63n(int64(c.maxDelay)))
+}
+
+// chaotic is a player with failures injected into it
+type chaotic struct {
+	name   string
+	player Player
+	chaos  *Chaos
+}
+
+func (c *chaotic) Setup() error {
+	if ok, d := c.chaos.strike(); ok {
+		time.Sleep(d)
+	}
+	return c.player.Setup()
+}
+
+func (c *chaotic) Play(ctx context.Context) error {
+	fail, failAfter := c.chaos.strike()
+	cancelEarly, cancelAfter := c.chaos.strike()
+	if !fail && !cancelEarly {
+		return c.player.Play(ctx)
+	}
+
+	pctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- c.player.Play(pctx)
+	}()
+
+	var failed, cancelled <-chan time.Time
+	if fail {
+		failed = time.After(failAfter)
+	}
+	if cancelEarly {
+		cancelled = time.After(cancelAfter)
+	}
+	for {
+		select {
+		case err := <-done:
+			return err
+		case <-cancelled:
+			cancelled = nil
+			cancel()
+		case <-failed:
+			// the real player is stopped, so it doesn't outlive its Play
+			cancel()
+			<-done
+			return ErrChaos{Player: c.name}
+		}
+	}
+}
+
+func (c *chaotic) Clean() {
+	if ok, d := c.chaos.strike(); ok {
+		time.Sleep(d)
+	}
+	c.player.Clean()
+}
+
+func (c *chaotic) CleanContext(ctx context.Context) {
+	if ok, d := c.chaos.strike(); ok {
+		sleep(ctx, d)
+	}
+	cleanPlayer(ctx, c.player)
+}
+
+func (c *chaotic) Unwrap() Player {
+	return c.player
+}
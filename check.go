@@ -0,0 +1,137 @@
+package orchestra
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// Violation is a break in the life cycle of a player, reported by `orchestra.Checked`
+type Violation struct {
+	Player string
+	Rule   string // the rule that was broken
+	Detail string // the history of the player up until the violation
+	Stack  []byte // the stack of the goroutine that broke the rule
+}
+
+func (v Violation) Error() string {
+	return fmt.Sprintf("Violation: %s: %s (%s)", v.Player, v.Rule, v.Detail)
+}
+
+// Checked returns a wrapper (meant for (*Stage).Wrap) that checks the life cycle of every player, and reports every violation to report:
+//
+//   - Play must only be called after a successful Setup, and before the Clean that follows it
+//   - Clean must be called exactly once for every successful Setup (a restarted player is setup again), and only after Play has returned
+//   - the context given to Play must have been cancelled by the time Clean is called
+//
+// If report is nil, violations panic instead. It's meant for tests, and canary deployments:
+//
+//	stage.Wrap(orchestra.Checked(func(v orchestra.Violation) { log.Printf("%v\n%s", v, v.Stack) }))
+func Checked(report func(Violation)) func(name string, p Player) Player {
+	if report == nil {
+		report = func(v Violation) { panic(v) }
+	}
+	return func(name string, p Player) Player {
+		return &checked{name: name, player: p, report: report}
+	}
+}
+
+// checked is a player that checks its own life cycle
+type checked struct {
+	name   string
+	player Player
+	report func(Violation)
+
+	mu      sync.Mutex
+	setups  int             // the number of times Setup was called
+	setup   bool            // the last Setup returned nil, and it hasn't been cleaned yet
+	plays   int             // the number of times Play was called
+	playing bool            // Play has been called and hasn't returned
+	ctx     context.Context // the context given to the last Play
+	cleans  int             // the number of times Clean was called
+	history []string
+}
+
+// note adds to the history of the player, the caller must hold the lock
+func (c *checked) note(format string, args ...interface{}) {
+	c.history = append(c.history, time.Now().Format("15:04:05.000000")+" "+fmt.Sprintf(format, args...))
+}
+
+// violate reports a violation, the caller must hold the lock
+func (c *checked) violate(rule string) {
+	c.note("violated: %s", rule)
+	v := Violation{
+		Player: c.name,
+		Rule:   rule,
+		Detail: fmt.Sprintf("setups: %d, plays: %d, cleans: %d, history: %v", c.setups, c.plays, c.cleans, c.history),
+		Stack:  debug.Stack(),
+	}
+	// report outside the lock, it may very well panic
+	c.mu.Unlock()
+	defer c.mu.Lock()
+	c.report(v)
+}
+
+func (c *checked) Setup() error {
+	err := c.player.Setup()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setups++
+	c.setup = err == nil
+	c.note("setup returned: %v", err)
+	return err
+}
+
+func (c *checked) Play(ctx context.Context) error {
+	c.mu.Lock()
+	c.plays++
+	c.note("play called")
+	if !c.setup {
+		c.violate("Play must only be called after a successful Setup, and before the Clean that follows it")
+	}
+	c.playing = true
+	c.ctx = ctx
+	c.mu.Unlock()
+
+	err := c.player.Play(ctx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.playing = false
+	c.note("play returned: %v", err)
+	return err
+}
+
+func (c *checked) Clean() {
+	c.cleaning()
+	c.player.Clean()
+}
+
+func (c *checked) CleanContext(ctx context.Context) {
+	c.cleaning()
+	cleanPlayer(ctx, c.player)
+}
+
+func (c *checked) Unwrap() Player {
+	return c.player
+}
+
+// cleaning checks the life cycle of the player right before it's cleaned
+func (c *checked) cleaning() {
+	c.mu.Lock()
+	c.cleans++
+	c.note("clean called")
+	if !c.setup {
+		c.violate("Clean must be called exactly once for every successful Setup")
+	}
+	c.setup = false
+	if c.playing {
+		c.violate("Clean must only be called after Play has returned")
+	}
+	if c.ctx != nil && c.ctx.Err() == nil {
+		c.violate("the context given to Play must be cancelled by the time Clean is called")
+	}
+	c.mu.Unlock()
+}
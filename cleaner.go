@@ -0,0 +1,59 @@
+package orchestra
+
+import (
+	"context"
+	"time"
+)
+
+// ContextCleaner can be implemented by players whose Clean can honor a deadline, the stage calls CleanContext instead of Clean,
+// with a context that's done once the shutdown budget of the stage runs out (see `orchestra.ShutdownBudget`), so a slow Clean can give up in time.
+// `*orchestra.Stage` implements it too, so nested stages are cleaned within what's left of the budget of their parent.
+type ContextCleaner interface {
+	CleanContext(ctx context.Context)
+}
+
+// CleanContext is (*Stage).Clean, except that the context given to the players that implement `orchestra.ContextCleaner` is done once ctx is,
+// or once the shutdown budget of the stage runs out (see (*Stage).Remaining), whichever comes first.
+func (s *Stage) CleanContext(ctx context.Context) {
+	if deadline, ok := s.deadline(); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+	s.clean(ctx)
+}
+
+// Remaining returns what's left of the shutdown budget of the stage (see `orchestra.ShutdownBudget`), it's false if the stage hasn't started shutting down,
+// or doesn't have a budget. The budget starts running out as soon as the context given to (*Stage).Play is done, and carries over to (*Stage).Clean.
+func (s *Stage) Remaining() (time.Duration, bool) {
+	deadline, ok := s.deadline()
+	if !ok {
+		return 0, false
+	}
+	return time.Until(deadline), true
+}
+
+// deadline returns when the shutdown budget of the stage runs out
+func (s *Stage) deadline() (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.shutdown, !s.shutdown.IsZero()
+}
+
+// shuttingDown starts the shutdown budget, if the stage has one, and it hasn't already started
+func (s *Stage) shuttingDown() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.budget > 0 && s.shutdown.IsZero() {
+		s.shutdown = time.Now().Add(s.budget)
+	}
+}
+
+// cleanPlayer calls the CleanContext of the player if it's a `orchestra.ContextCleaner`, or its Clean otherwise
+func cleanPlayer(ctx context.Context, p Player) {
+	if c, ok := p.(ContextCleaner); ok {
+		c.CleanContext(ctx)
+		return
+	}
+	p.Clean()
+}
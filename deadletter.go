@@ -0,0 +1,42 @@
+package orchestra
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// DeadLetter receives the errors that were absorbed by restarts (see `orchestra.Restart`), so they aren't lost just because the player recovered.
+// Absorb is called from the goroutine of the failed player, so it must be safe for concurrent use
+type DeadLetter interface {
+	Absorb(Event)
+}
+
+// DeadLetterFunc is a function that can be used as a `orchestra.DeadLetter`
+type DeadLetterFunc func(Event)
+
+// Absorb calls the function
+func (f DeadLetterFunc) Absorb(e Event) {
+	f(e)
+}
+
+// DeadLetterChan sends the absorbed errors to ch, the errors are dropped if ch isn't ready to receive, so the restarts are never held up
+func DeadLetterChan(ch chan<- Event) DeadLetter {
+	return DeadLetterFunc(func(e Event) {
+		select {
+		case ch <- e:
+		default:
+		}
+	})
+}
+
+// DeadLetterWriter writes the absorbed errors to w as lines of JSON, in the same format as `orchestra.Journal`
+func DeadLetterWriter(w io.Writer) DeadLetter {
+	return DeadLetterFunc((&journal{enc: json.NewEncoder(w)}).write)
+}
+
+// DeadLetters makes the stage hand every error absorbed by a restart to d
+func DeadLetters(d DeadLetter) StageOption {
+	return func(s *Stage) {
+		s.deadLetter = d
+	}
+}
@@ -0,0 +1,93 @@
+package orchestra
+
+import (
+	"fmt"
+	"slices"
+)
+
+// DiffKind is how a player differs between two stages, see `orchestra.Diff`
+type DiffKind string
+
+const (
+	DiffAdded   DiffKind = "added"
+	DiffRemoved DiffKind = "removed"
+	DiffChanged DiffKind = "changed"
+)
+
+// Difference is a single difference between two stages, see `orchestra.Diff`
+type Difference struct {
+	Player string   `json:"player"`           // the path of the player, for eg, "workers/consumer", or the path of a stage followed by "/" if it's the stage's own options
+	Kind   DiffKind `json:"kind"`             // the kind of the difference
+	Before []string `json:"before,omitempty"` // the type of the player, and its options (as in (*Stage).Dump) in the old stage, empty if it was added
+	After  []string `json:"after,omitempty"`  // the type of the player, and its options in the new stage, empty if it was removed
+}
+
+func (d Difference) String() string {
+	return fmt.Sprintf("%s %s: %v -> %v", d.Kind, d.Player, d.Before, d.After)
+}
+
+// Diff compares the players, their options, and their dependencies on two stages (from the old one, to the new one), in a machine-readable form, for eg,
+// to show what a change to a config driven stage would do, before it's applied.
+// The players are matched by name, and nested stages are compared recursively. The stages are compared as they were built, their state isn't.
+func Diff(from, to *Stage) []Difference {
+	return diff("", from, to)
+}
+
+func diff(prefix string, from, to *Stage) []Difference {
+	var diffs []Difference
+	if before, after := from.describe(), to.describe(); !slices.Equal(before, after) {
+		diffs = append(diffs, Difference{Player: prefix + "/", Kind: DiffChanged, Before: before, After: after})
+	}
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	from.mu.Lock()
+	fromPlayers, fromOrder := copyPlayers(from)
+	from.mu.Unlock()
+	to.mu.Lock()
+	toPlayers, toOrder := copyPlayers(to)
+	to.mu.Unlock()
+
+	for _, name := range fromOrder {
+		o := fromPlayers[name]
+		n, ok := toPlayers[name]
+		if !ok {
+			diffs = append(diffs, Difference{Player: prefix + name, Kind: DiffRemoved, Before: o.describe()})
+			continue
+		}
+		if before, after := o.describe(), n.describe(); !slices.Equal(before, after) {
+			diffs = append(diffs, Difference{Player: prefix + name, Kind: DiffChanged, Before: before, After: after})
+		}
+		if o.nested != nil && n.nested != nil {
+			diffs = append(diffs, diff(prefix+name, o.nested, n.nested)...)
+		}
+	}
+	for _, name := range toOrder {
+		if _, ok := fromPlayers[name]; !ok {
+			diffs = append(diffs, Difference{Player: prefix + name, Kind: DiffAdded, After: toPlayers[name].describe()})
+		}
+	}
+	return diffs
+}
+
+// copyPlayers returns the players of the stage, and their order, the caller must hold (*Stage).mu
+func copyPlayers(s *Stage) (map[string]*entry, []string) {
+	players := make(map[string]*entry, len(s.players))
+	for name, e := range s.players {
+		players[name] = e
+	}
+	return players, slices.Clone(s.order)
+}
+
+// describe describes the player for `orchestra.Diff`
+func (e *entry) describe() []string {
+	return append([]string{fmt.Sprintf("type=%T", e.player)}, e.options()...)
+}
+
+// describe describes the stage for `orchestra.Diff`
+func (s *Stage) describe() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.options()
+}
@@ -0,0 +1,234 @@
+package orchestra
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Dump writes a diagnostic snapshot of the stage to w: the state of every player, how long they took,
+// how they were configured, how they depend on each other, and the recent errors.
+// Nested stages are dumped along with their parent.
+// It is safe to call Dump at any time, even while the stage is playing.
+func (s *Stage) Dump(w io.Writer) error {
+	b := &strings.Builder{}
+	s.dump(b, "")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (s *Stage) dump(b *strings.Builder, indent string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+
+	fmt.Fprintf(b, "%sstage: %d players, setup: %t, config: %s\n", indent, len(s.order), s.beenSetup, s.config())
+
+	tw := tabwriter.NewWriter(b, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "%sPLAYER\tSTATE\tSETUP\tPLAY\tCONFIG\tERROR\n", indent)
+	var nested []*entry
+	for _, name := range s.order {
+		e := s.players[name]
+		play := "-"
+		if !e.status.started.IsZero() {
+			stopped := e.status.stopped
+			if stopped.IsZero() {
+				stopped = now
+			}
+			play = stopped.Sub(e.status.started).String()
+		}
+		setup := "-"
+		if e.status.state != StateAdded {
+			setup = e.status.setupTook.String()
+			if e.status.warmupTook > 0 {
+				setup += " (warmup " + e.status.warmupTook.String() + ")"
+			}
+		}
+		errs := "-"
+		if e.status.err != nil {
+			errs = e.status.err.Error()
+		}
+		state := e.status.state.String()
+		if e.nested != nil && e.nested.empty() {
+			// the nested stage has its own lock, so it's fine to look at it while holding ours
+			state += " (empty)"
+		}
+		fmt.Fprintf(tw, "%s%s\t%s\t%s\t%s\t%s\t%s\n", indent, name, state, setup, play, e.config(), errs)
+		if e.nested != nil {
+			nested = append(nested, e)
+		}
+	}
+	tw.Flush()
+
+	fmt.Fprintf(b, "%sdependencies:\n", indent)
+	for _, name := range s.order {
+		for _, dep := range s.players[name].after {
+			fmt.Fprintf(b, "%s  %s -> %s\n", indent, name, dep)
+		}
+	}
+
+	s.dumpGroups(b, indent)
+
+	if s.startup != nil {
+		s.startup.write(b, indent)
+	}
+
+	fmt.Fprintf(b, "%srecent errors:\n", indent)
+	for _, r := range s.recent {
+		fmt.Fprintf(b, "%s  %s %s %s: %s\n", indent, r.at.Format(time.RFC3339Nano), r.player, r.phase, r.err)
+	}
+
+	for _, e := range nested {
+		fmt.Fprintf(b, "%snested %s:\n", indent, e.name)
+		// the nested stage has its own lock, so it's fine to dump it while holding ours
+		e.nested.dump(b, indent+"  ")
+	}
+}
+
+// config describes the options the player was added with
+func (e *entry) config() string {
+	opts := e.options()
+	if len(opts) == 0 {
+		return "-"
+	}
+	return strings.Join(opts, " ")
+}
+
+// options lists the options the player was added with, see (*entry).config
+func (e *entry) options() []string {
+	var opts []string
+	if len(e.after) > 0 {
+		opts = append(opts, "after="+strings.Join(e.after, ","))
+	}
+	if e.latch != nil {
+		opts = append(opts, "latched")
+	}
+	if e.gate != nil {
+		opts = append(opts, "gated")
+	}
+	if e.exclusive != "" {
+		opts = append(opts, "exclusive="+e.exclusive)
+	}
+	for _, name := range e.resources() {
+		opts = append(opts, fmt.Sprintf("weight=%s:%d", name, e.weights[name]))
+	}
+	if e.timeout > 0 {
+		opts = append(opts, "timeout="+e.timeout.String())
+	}
+	if e.restart.max != 0 {
+		opts = append(opts, fmt.Sprintf("restart=%d/%s", e.restart.max, e.restart.backoff))
+	}
+	if e.warmupTimeout > 0 {
+		opts = append(opts, "warmup-timeout="+e.warmupTimeout.String())
+	}
+	if e.health.k > 1 {
+		opts = append(opts, fmt.Sprintf("hysteresis=%d", e.health.k))
+	}
+	if e.scratch {
+		opts = append(opts, "scratch")
+	}
+	if e.idleAfter > 0 {
+		opts = append(opts, "idle-after="+e.idleAfter.String())
+	}
+	if e.lowPriority {
+		opts = append(opts, "low-priority")
+	}
+	if e.critical {
+		opts = append(opts, "critical-clean")
+	}
+	if e.quarantine > 0 {
+		opts = append(opts, fmt.Sprintf("quarantine=%d", e.quarantine))
+	}
+	if e.init {
+		opts = append(opts, "init")
+	}
+	if e.group != "" {
+		opts = append(opts, "group="+e.group)
+	}
+	if e.lifetime.max > 0 {
+		opts = append(opts, fmt.Sprintf("lifetime=%s+%s", e.lifetime.max, e.lifetime.jitter))
+	}
+	if e.nonCritical {
+		opts = append(opts, "non-critical")
+	} else if e.optional {
+		opts = append(opts, "optional")
+	}
+	if e.stopOrder != 0 {
+		opts = append(opts, fmt.Sprintf("stop-order=%d", e.stopOrder))
+	}
+	if e.window != nil {
+		opts = append(opts, fmt.Sprintf("window=%v", e.window))
+	}
+	return opts
+}
+
+// config describes the options the stage was created with
+func (s *Stage) config() string {
+	opts := s.options()
+	if len(opts) == 0 {
+		return "-"
+	}
+	return strings.Join(opts, " ")
+}
+
+// options lists the options the stage was created with, see (*Stage).config
+func (s *Stage) options() []string {
+	var opts []string
+	if s.sequential {
+		opts = append(opts, "sequential")
+	}
+	names := make([]string, 0, len(s.resources))
+	for name := range s.resources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		opts = append(opts, fmt.Sprintf("resource=%s:%d", name, s.resources[name].size))
+	}
+	if s.onError != nil {
+		opts = append(opts, "on-error")
+	}
+	if s.journal != nil {
+		opts = append(opts, "journal")
+	}
+	if s.deadLetter != nil {
+		opts = append(opts, "dead-letters")
+	}
+	if s.reporter != nil {
+		opts = append(opts, "reporter")
+	}
+	if s.recover {
+		opts = append(opts, "recover")
+	}
+	if s.logger != nil {
+		opts = append(opts, "logger")
+	}
+	if s.meter != nil {
+		opts = append(opts, "metrics")
+	}
+	if s.history != nil {
+		opts = append(opts, "crash-history")
+	}
+	if s.inspect != nil {
+		opts = append(opts, "inspect-on-signal")
+	}
+	if s.store != nil {
+		opts = append(opts, "persist")
+	}
+	if s.exitCode != 1 {
+		opts = append(opts, fmt.Sprintf("exit-code=%d", s.exitCode))
+	}
+	if s.budget > 0 {
+		opts = append(opts, "shutdown-budget="+s.budget.String())
+	}
+	if s.whenEmpty != ModeBatch {
+		opts = append(opts, "when-empty="+s.whenEmpty.String())
+	}
+	for _, a := range s.alarms {
+		opts = append(opts, fmt.Sprintf("alert=%d/%s", a.threshold.Failures, a.threshold.Within))
+	}
+	return opts
+}
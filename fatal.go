@@ -0,0 +1,159 @@
+package orchestra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// ErrFatal is the error a player is failed with, when it calls `orchestra.Fatal`
+type ErrFatal struct {
+	Player string
+	Err    error
+}
+
+func (e ErrFatal) Error() string {
+	return fmt.Sprintf("ErrFatal: %s: %s", e.Player, e.Err)
+}
+
+func (e ErrFatal) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode sets the code the process exits with when a player calls `orchestra.Fatal`, it's 1 by default.
+// Only the code of the outermost stage counts, as the players of nested stages take the whole process down with them.
+func ExitCode(code int) StageOption {
+	return func(s *Stage) {
+		s.exitCode = code
+	}
+}
+
+// CriticalClean makes the stage clean the player even when the process is taken down by `orchestra.Fatal`, for eg, to release a lock, or flush a journal.
+// The rest of the players aren't cleaned at all in that case. If the player is playing, it's cancelled, and cleaned once its Play returns,
+// or once the shutdown budget runs out (see `orchestra.ShutdownBudget`), whichever comes first.
+func CriticalClean() Option {
+	return func(e *entry) {
+		e.critical = true
+	}
+}
+
+// Fatal takes the process down right away, bypassing the graceful shutdown of the stage, it's meant for players that detect unrecoverable conditions, like corrupt state.
+// The error is logged (see `orchestra.Logger`), recorded, and reported as the failure of the player the context was given to, wrapped in `ErrFatal`,
+// then the players added with `orchestra.CriticalClean` anywhere on the outermost stage (nested stages included) are cleaned, in the reverse order they were setup,
+// and the process exits with the code set by `orchestra.ExitCode`. No player is played, or restarted once Fatal has been called.
+// Only the first call to Fatal has any effect, the rest block forever, as does the first one once the process has exited.
+// If the context wasn't given to a player by a stage, the error is logged to slog's default logger, and the process exits with 1.
+//
+// Note: the player calling Fatal (and the nested stages it's on) can't return from its Play, so if it's added with `orchestra.CriticalClean`,
+// it's cleaned while its Play is still blocked in Fatal.
+//
+// Note: it never returns, so it's preferred over calling os.Exit behind the stage's back, which skips all of the above
+func Fatal(ctx context.Context, err error) {
+	p, ok := playerOf(ctx)
+	if !ok {
+		slog.Error("fatal", "error", err)
+		os.Exit(1)
+	}
+	p.stage.fatal(p.entry, err)
+}
+
+// errFatal is the cause the players are cancelled with, when the process is taken down by `orchestra.Fatal`
+var errFatal = errors.New("fatal")
+
+// fatal takes the process down, see `orchestra.Fatal`
+func (s *Stage) fatal(it *entry, err error) {
+	root := s
+	for root.owner != nil {
+		root = root.owner.stage
+	}
+	first := false
+	root.fatally.Do(func() { first = true })
+	if !first {
+		select {}
+	}
+	root.die()
+
+	err = ErrFatal{Player: it.name, Err: err}
+	s.log(slog.LevelError, "fatal", "player", it.name, "error", err)
+	if s.logger == nil {
+		slog.Error("fatal", "player", it.name, "error", err) // it's taking the process down, it can't go unlogged
+	}
+	s.transition(it, StateFailed, PhasePlay, err)
+
+	// the players that can't return, as they're blocked in this very call
+	stuck := map[*entry]bool{it: true}
+	for n := s; n.owner != nil; n = n.owner.stage {
+		stuck[n.owner.entry] = true
+	}
+
+	root.shuttingDown()
+	ctx := context.Background()
+	if deadline, ok := root.deadline(); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+	critical := root.critical()
+	var returned []chan struct{}
+	for _, c := range critical {
+		c.stage.mu.Lock()
+		if c.entry.cancel != nil && !stuck[c.entry] {
+			c.entry.cancel(errFatal)
+			returned = append(returned, c.entry.returned)
+		}
+		c.stage.mu.Unlock()
+	}
+	for _, r := range returned {
+		select {
+		case <-r:
+		case <-ctx.Done():
+		}
+	}
+	for _, c := range critical {
+		cleanPlayer(ctx, c.entry.player)
+		c.entry.removeScratch()
+		c.stage.transition(c.entry, StateCleaned, PhaseClean, nil)
+	}
+
+	os.Exit(root.exitCode)
+}
+
+// die keeps the players of the stage, and of the stages nested in it, from being played, or restarted again, see `orchestra.Fatal`
+func (s *Stage) die() {
+	s.mu.Lock()
+	s.dying = true
+	var nested []*Stage
+	for _, it := range s.players {
+		if it.nested != nil {
+			nested = append(nested, it.nested)
+		}
+	}
+	s.mu.Unlock()
+	for _, n := range nested {
+		n.die()
+	}
+}
+
+// critical returns the players added with `orchestra.CriticalClean` on the stage, and the stages nested in it, in the order they're cleaned in
+func (s *Stage) critical() []owned {
+	s.mu.Lock()
+	setup := s.setup
+	s.mu.Unlock()
+	var critical []owned
+	for i := len(setup) - 1; i >= 0; i-- {
+		c := setup[i]
+		s.mu.Lock()
+		broken := c.broken
+		s.mu.Unlock()
+		switch {
+		case broken:
+		case c.critical:
+			critical = append(critical, owned{stage: s, entry: c})
+		case c.nested != nil:
+			critical = append(critical, c.nested.critical()...)
+		}
+	}
+	return critical
+}
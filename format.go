@@ -0,0 +1,64 @@
+package orchestra
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Format implements fmt.Formatter, %v and %s print the compact message, whereas %+v prints
+// a detailed view with the whole chain of the error returned by the player, and how long its setup took
+func (e ErrSetup) Format(f fmt.State, verb rune) {
+	if verb != 'v' || !f.Flag('+') {
+		compact(f, verb, e.Error())
+		return
+	}
+	fmt.Fprintf(f, "ErrSetup: %s (setup took %s)\n", e.Player, e.Duration)
+	io.WriteString(f, detail(e.Err, "  "))
+}
+
+// Format implements fmt.Formatter, %v and %s print the compact message, whereas %+v prints
+// a detailed view with the whole chain of the error returned by every failed player, and how long each of them played
+func (e *ErrPlay) Format(f fmt.State, verb rune) {
+	if verb != 'v' || !f.Flag('+') {
+		compact(f, verb, e.Error())
+		return
+	}
+	fmt.Fprintf(f, "ErrPlay: %d players failed\n", len(e.Players))
+	for _, name := range e.names() {
+		if d, ok := e.Durations[name]; ok {
+			fmt.Fprintf(f, "  %s (played %s):\n", name, d)
+		} else {
+			fmt.Fprintf(f, "  %s:\n", name)
+		}
+		io.WriteString(f, detail(e.Players[name], "    "))
+	}
+}
+
+// compact writes the message as the verb would for a plain string
+func compact(f fmt.State, verb rune, msg string) {
+	switch verb {
+	case 'q':
+		fmt.Fprintf(f, "%q", msg)
+	default:
+		io.WriteString(f, msg)
+	}
+}
+
+// detail describes err, one line per error in its chain, all indented.
+// errors that know how to print their own details (like the errors of a nested stage) are asked to do so
+func detail(err error, indent string) string {
+	b := &strings.Builder{}
+	prefix := ""
+	for ; err != nil; err = errors.Unwrap(err) {
+		msg := err.Error()
+		if _, ok := err.(fmt.Formatter); ok {
+			msg = strings.TrimRight(fmt.Sprintf("%+v", err), "\n")
+		}
+		msg = strings.ReplaceAll(msg, "\n", "\n"+indent)
+		fmt.Fprintf(b, "%s%s%s\n", indent, prefix, msg)
+		prefix = "caused by: "
+	}
+	return b.String()
+}
@@ -0,0 +1,194 @@
+package orchestra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// group is a namespace of players, see `orchestra.Group`. It's guarded by (*Stage).mu
+type group struct {
+	quota   *semaphore    // nil if the group has no quota
+	resumed chan struct{} // closed when the group is resumed, nil if it isn't paused
+	stopped bool
+}
+
+// Group puts the player in the named group (for eg, a tenant, or a subsystem), so it can be paused, resumed, or stopped along with the rest of the group,
+// and be limited by the quota of the group (see `orchestra.GroupQuota`).
+// The group of the player is included in its events (see `orchestra.Event`), and in (*Stage).Dump
+func Group(name string) Option {
+	return func(e *entry) {
+		e.group = name
+	}
+}
+
+// ErrQuota is the error (wrapped in `ErrSetup`) when a player is in a group whose quota can never let it Play, see `orchestra.GroupQuota`
+type ErrQuota struct {
+	Player string
+	Group  string
+	Quota  int
+}
+
+func (e ErrQuota) Error() string {
+	return fmt.Sprintf("ErrQuota: %s: %s: quota %d is less than 1", e.Player, e.Group, e.Quota)
+}
+
+// GroupQuota limits the number of players of the named group that can Play at once, the rest wait for their turn.
+// The quota must be at least 1, otherwise the stage fails to setup with `ErrQuota`, instead of its players waiting forever
+func GroupQuota(name string, n int) StageOption {
+	return func(s *Stage) {
+		s.groupOf(name).quota = newSemaphore(n)
+	}
+}
+
+// quotaOf returns `ErrQuota` if the quota of the player's group can never let it Play
+func (s *Stage) quotaOf(it *entry) error {
+	if it.group == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if g, ok := s.groups[it.group]; ok && g.quota != nil && g.quota.size < 1 {
+		return ErrQuota{Player: it.name, Group: it.group, Quota: g.quota.size}
+	}
+	return nil
+}
+
+// groupOf returns the named group, creating it if it doesn't exist yet. The caller must hold (*Stage).mu if the stage is playing
+func (s *Stage) groupOf(name string) *group {
+	if s.groups == nil {
+		s.groups = make(map[string]*group)
+	}
+	g, ok := s.groups[name]
+	if !ok {
+		g = &group{}
+		s.groups[name] = g
+	}
+	return g
+}
+
+var (
+	errPaused  = errors.New("group paused")
+	errStopped = errors.New("group stopped")
+)
+
+// PauseGroup cancels the Play of every player in the named group, and holds them until the group is resumed with (*Stage).ResumeGroup.
+// The players aren't cleaned while the group is paused, their Play is just called again once it's resumed,
+// and the errors they return when they're paused are discarded. The pause is persisted if the stage is configured to, see `orchestra.Persist`.
+func (s *Stage) PauseGroup(name string) {
+	defer s.persist()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g := s.groupOf(name)
+	if g.resumed == nil {
+		g.resumed = make(chan struct{})
+	}
+	s.cancelGroup(name, errPaused)
+}
+
+// ResumeGroup lets the players of the named group Play again, after (*Stage).PauseGroup
+func (s *Stage) ResumeGroup(name string) {
+	defer s.persist()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g := s.groupOf(name)
+	if g.resumed != nil {
+		close(g.resumed)
+		g.resumed = nil
+	}
+}
+
+// StopGroup cancels the Play of every player in the named group, and keeps them from playing again, as if they returned nil.
+// The rest of the stage keeps playing, and the players are cleaned along with it. The group plays again once the stage is setup again.
+func (s *Stage) StopGroup(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.groupOf(name).stopped = true
+	s.cancelGroup(name, errStopped)
+}
+
+// cancelGroup cancels the players of the group that are playing, the caller must hold (*Stage).mu
+func (s *Stage) cancelGroup(name string, cause error) {
+	for _, it := range s.players {
+		if it.group == name && it.cancel != nil {
+			it.cancel(cause)
+		}
+	}
+}
+
+// enter waits until the player's group lets it play, and acquires the quota of the group.
+// it returns a func that gives the quota back, or errSkipped if the player mustn't play, or if ctx is done while it waits
+func (s *Stage) enter(ctx context.Context, it *entry) (func(), error) {
+	for {
+		s.mu.Lock()
+		g := s.groupOf(it.group)
+		stopped, resumed := g.stopped, g.resumed
+		s.mu.Unlock()
+		if stopped {
+			return nil, errSkipped
+		}
+		if resumed == nil {
+			break
+		}
+		s.transition(it, StateWaiting, PhasePlay, nil)
+		select {
+		case <-resumed:
+		case <-ctx.Done():
+			return nil, errSkipped
+		}
+	}
+	s.mu.Lock()
+	quota := s.groupOf(it.group).quota
+	s.mu.Unlock()
+	if quota == nil {
+		return func() {}, nil
+	}
+	s.transition(it, StateWaiting, PhasePlay, nil)
+	if quota.acquire(ctx, 1) != nil {
+		return nil, errSkipped
+	}
+	s.mu.Lock()
+	stopped := s.groupOf(it.group).stopped // it may have been stopped while the player waited for its turn
+	s.mu.Unlock()
+	if stopped {
+		quota.release(1)
+		return nil, errSkipped
+	}
+	return func() { quota.release(1) }, nil
+}
+
+// dumpGroups describes every group of the stage, the caller must hold (*Stage).mu
+func (s *Stage) dumpGroups(b *strings.Builder, indent string) {
+	if len(s.groups) == 0 {
+		return
+	}
+	names := make([]string, 0, len(s.groups))
+	for name := range s.groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintf(b, "%sgroups:\n", indent)
+	for _, name := range names {
+		g := s.groups[name]
+		var players []string
+		for _, p := range s.order {
+			if s.players[p].group == name {
+				players = append(players, p)
+			}
+		}
+		state := "playing"
+		switch {
+		case g.stopped:
+			state = "stopped"
+		case g.resumed != nil:
+			state = "paused"
+		}
+		quota := "-"
+		if g.quota != nil {
+			quota = fmt.Sprint(g.quota.size)
+		}
+		fmt.Fprintf(b, "%s  %s: %s, quota: %s, players: %s\n", indent, name, state, quota, strings.Join(players, ","))
+	}
+}
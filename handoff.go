@@ -0,0 +1,26 @@
+package orchestra
+
+// Resumer can be implemented by players that want to resume where they left off when they're restarted (see `orchestra.Restart`, and `orchestra.MaxLifetime`),
+// instead of starting cold, for eg, a consumer handing over its offsets.
+//
+// Before the player is cleaned for a restart, Export is called to get a small blob of its state,
+// and right before it's setup again, Resume is called with that blob, so its Setup can pick up from there.
+// If the player fails to setup again, the same blob is handed over on the next attempt.
+type Resumer interface {
+	Export() []byte
+	Resume(state []byte)
+}
+
+// export saves the state of the player, if it's a resumer, before it's cleaned for a restart
+func (it *entry) export() {
+	if r, ok := as[Resumer](it.player); ok && !it.broken {
+		it.handoff = r.Export()
+	}
+}
+
+// resume hands the saved state over to the player, if it's a resumer, before it's setup again after a restart
+func (it *entry) resume() {
+	if r, ok := as[Resumer](it.player); ok && it.handoff != nil {
+		r.Resume(it.handoff)
+	}
+}
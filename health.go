@@ -0,0 +1,109 @@
+package orchestra
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// HealthChecker can be implemented by players that can tell whether they're healthy, for eg, a connection pool pinging its database.
+// The health of every such player is aggregated by (*Stage).Health. Since `*orchestra.Stage` implements it too, nested stages are aggregated along with their parent.
+type HealthChecker interface {
+	Health(ctx context.Context) error
+}
+
+// ErrUnhealthy is returned by (*Stage).Health, with the last error of every player that is considered unhealthy
+type ErrUnhealthy struct {
+	Players map[string]error
+}
+
+func (e ErrUnhealthy) Error() string {
+	names := make([]string, 0, len(e.Players))
+	for name := range e.Players {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	str := "ErrUnhealthy:"
+	for _, name := range names {
+		str += fmt.Sprintf(" |%s: %s|", name, e.Players[name])
+	}
+	return str
+}
+
+// health is the smoothed health of a player, see `orchestra.Hysteresis`. It's guarded by (*Stage).mu
+type health struct {
+	k         int // the number of consecutive checks needed to flip, zero is the same as one
+	unhealthy bool
+	streak    int   // the number of consecutive checks that disagreed with the current health
+	err       error // the last error returned by the check
+}
+
+// observe records the result of a check, flipping the health only after k consecutive checks agree
+func (h *health) observe(err error) {
+	if err != nil {
+		h.err = err
+	}
+	if (err != nil) == h.unhealthy {
+		h.streak = 0
+		return
+	}
+	h.streak++
+	if h.streak >= h.k {
+		h.unhealthy = !h.unhealthy
+		h.streak = 0
+	}
+}
+
+// Hysteresis keeps a flapping player from flipping the health of the stage rapidly (see (*Stage).Health),
+// the player is considered unhealthy only after k consecutive failed checks, and healthy again only after k consecutive successful ones.
+// By default, every check counts.
+func Hysteresis(k int) Option {
+	return func(e *entry) {
+		e.health.k = k
+	}
+}
+
+// Health checks every player that implements `orchestra.HealthChecker` concurrently, and returns `ErrUnhealthy` if any of them is unhealthy, as per `orchestra.Hysteresis`.
+// Every call counts as a check, so it's meant to be called periodically, for eg, by a readiness probe.
+// Players that aren't setup aren't checked.
+func (s *Stage) Health(ctx context.Context) error {
+	s.mu.Lock()
+	var checked []*entry
+	for _, it := range s.setup {
+		if _, ok := as[HealthChecker](it.player); ok && !it.broken {
+			checked = append(checked, it)
+		}
+	}
+	s.mu.Unlock()
+
+	errs := make([]error, len(checked))
+	wg := &sync.WaitGroup{}
+	wg.Add(len(checked))
+	for i, it := range checked {
+		go func(i int, it *entry) {
+			defer wg.Done()
+			h, _ := as[HealthChecker](it.player)
+			errs[i] = h.Health(ctx)
+		}(i, it)
+	}
+	wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var err *ErrUnhealthy
+	for i, it := range checked {
+		it.health.observe(errs[i])
+		if !it.health.unhealthy {
+			continue
+		}
+		if err == nil {
+			err = &ErrUnhealthy{Players: make(map[string]error)}
+		}
+		err.Players[it.name] = it.health.err
+	}
+	if err == nil {
+		return nil
+	}
+	return *err
+}
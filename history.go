@@ -0,0 +1,197 @@
+package orchestra
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Crashes is the crash history of a player, see `orchestra.History`
+type Crashes struct {
+	Count int       `json:"count"` // the number of times the player crashed in a row
+	First time.Time `json:"first"` // when the first of those crashes happened
+	Last  time.Time `json:"last"`  // when the last of those crashes happened
+}
+
+// History is where a stage records the crash history of its players, so it survives restarts of the process, see `orchestra.CrashHistory`.
+// The players are keyed by their names, prefixed by the names of the stages they are nested in, for eg, "workers/consumer".
+// Note: the history may be accessed concurrently from multiple players' goroutines
+type History interface {
+	Get(player string) (Crashes, error) // returns the zero value if there's no history of the player
+	Put(player string, c Crashes) error
+}
+
+// ErrQuarantined is returned when a player is kept from being setup, because it has crashed too many times in a row, see `orchestra.Quarantine`
+type ErrQuarantined struct {
+	Player  string
+	Crashes Crashes
+}
+
+func (e ErrQuarantined) Error() string {
+	return fmt.Sprintf("ErrQuarantined: %s: crashed %d times in a row since %s", e.Player, e.Crashes.Count, e.Crashes.First.Format(time.RFC3339))
+}
+
+// CrashHistory makes the stage record every crash of its players (i.e. every time their Play returns an error) to h,
+// and forget them once they return nil. The crashes that happened more than window ago (if it's non-zero) are forgotten too.
+// It makes the restart, and quarantine decisions survive restarts of the process, see `orchestra.Restart`, and `orchestra.Quarantine`:
+// a player that has used up its restarts before the process restarted, isn't restarted again after it.
+// Nested stages use the history of their parent, unless they have their own. Errors while accessing the history are logged, and otherwise ignored.
+func CrashHistory(h History, window time.Duration) StageOption {
+	return func(s *Stage) {
+		s.history = h
+		s.crashWindow = window
+	}
+}
+
+// Quarantine keeps the player from being setup once it has crashed n times in a row, as per the history of the stage (see `orchestra.CrashHistory`),
+// failing its setup with `ErrQuarantined` instead, so a crash looping player doesn't take the process down with it on every boot.
+// It's meant to be used along with `orchestra.NonCritical`. The quarantine is lifted once the crashes are forgotten.
+func Quarantine(n int) Option {
+	return func(e *entry) {
+		e.quarantine = n
+	}
+}
+
+// key returns the key of the player in the history
+func (s *Stage) key(it *entry) string {
+	if s.path == "" {
+		return it.name
+	}
+	return s.path + "/" + it.name
+}
+
+// crashes returns the crash history of the player, it's empty if the stage doesn't have a history
+func (s *Stage) crashes(it *entry) Crashes {
+	if s.history == nil {
+		return Crashes{}
+	}
+	c, err := s.history.Get(s.key(it))
+	if err != nil {
+		s.log(slog.LevelWarn, "failed to get crash history", "player", it.name, "error", err)
+		return Crashes{}
+	}
+	if s.crashWindow > 0 && time.Since(c.Last) > s.crashWindow {
+		return Crashes{}
+	}
+	return c
+}
+
+// quarantined returns `ErrQuarantined` if the player has crashed too many times in a row
+func (s *Stage) quarantined(it *entry) error {
+	if it.quarantine <= 0 {
+		return nil
+	}
+	if c := s.crashes(it); c.Count >= it.quarantine {
+		return ErrQuarantined{Player: it.name, Crashes: c}
+	}
+	return nil
+}
+
+// crashed records a crash of the player
+func (s *Stage) crashed(it *entry) {
+	if s.history == nil {
+		return
+	}
+	c := s.crashes(it)
+	now := time.Now()
+	if c.Count == 0 {
+		c.First = now
+	}
+	c.Count++
+	c.Last = now
+	if err := s.history.Put(s.key(it), c); err != nil {
+		s.log(slog.LevelWarn, "failed to put crash history", "player", it.name, "error", err)
+	}
+}
+
+// forget forgets the crashes of the player, once it returns nil
+func (s *Stage) forget(it *entry) {
+	if s.history == nil {
+		return
+	}
+	if err := s.history.Put(s.key(it), Crashes{}); err != nil {
+		s.log(slog.LevelWarn, "failed to put crash history", "player", it.name, "error", err)
+	}
+}
+
+// fileHistory is the history returned by `orchestra.FileHistory`
+type fileHistory struct {
+	mu   sync.Mutex
+	path string
+}
+
+// FileHistory returns a history that's kept in the file at path, as JSON. The file is created when the first crash is recorded,
+// and replaced atomically on every write, so it's never left half written.
+func FileHistory(path string) History {
+	return &fileHistory{path: path}
+}
+
+func (h *fileHistory) read() (map[string]Crashes, error) {
+	all := make(map[string]Crashes)
+	b, err := os.ReadFile(h.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return all, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &all); err != nil {
+		return nil, err
+	}
+	return all, nil
+}
+
+func (h *fileHistory) Get(player string) (Crashes, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	all, err := h.read()
+	if err != nil {
+		return Crashes{}, err
+	}
+	return all[player], nil
+}
+
+func (h *fileHistory) Put(player string, c Crashes) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	all, err := h.read()
+	if err != nil {
+		return err
+	}
+	if _, ok := all[player]; !ok && c.Count == 0 {
+		return nil // there's nothing to forget
+	}
+	if c.Count == 0 {
+		delete(all, player)
+	} else {
+		all[player] = c
+	}
+	b, err := json.MarshalIndent(all, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(h.path, b)
+}
+
+// writeFile replaces the file at path with b atomically, by writing to a temporary file next to it, and renaming it
+func writeFile(path string, b []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,75 @@
+package orchestra
+
+import "context"
+
+// Hooks returns a pair of start and stop hooks for the stage, in the shape used by the lifecycles of DI containers like uber's fx:
+//
+//	start, stop := stage.Hooks()
+//	lc.Append(fx.Hook{OnStart: start, OnStop: stop})
+//
+// start sets the stage up and plays it in the background, returning the error from Setup (if any).
+// stop cancels the stage, waits for its Play to return, cleans it, and returns the error from Play.
+// If the context given to stop is done before the stage stops playing, stop returns its error, and the stage isn't cleaned.
+func (s *Stage) Hooks() (start, stop func(context.Context) error) {
+	var cancel context.CancelFunc
+	var played chan error
+	start = func(context.Context) error {
+		if err := s.Setup(); err != nil {
+			return err
+		}
+		// the context given to start only bounds the start up, so it can't be the context the stage plays with
+		var ctx context.Context
+		ctx, cancel = context.WithCancel(context.Background())
+		played = make(chan error, 1)
+		go func() {
+			played <- s.Play(ctx)
+		}()
+		return nil
+	}
+	stop = func(ctx context.Context) error {
+		if played == nil {
+			return nil // never started
+		}
+		cancel()
+		select {
+		case err := <-played:
+			s.Clean()
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return start, stop
+}
+
+// hooks is the player returned by `orchestra.FromHooks`
+type hooks struct {
+	onStart, onStop func(context.Context) error
+}
+
+// FromHooks adapts a pair of start and stop hooks (like the ones registered on the lifecycles of DI containers like uber's fx) into a player.
+// Its Play calls onStart, blocks until the context is cancelled, and then calls onStop.
+// Either of them may be nil.
+// Note: onStop is called with a context that's never cancelled, as the one given to Play already is, so it's up to onStop to bound itself
+func FromHooks(onStart, onStop func(context.Context) error) Player {
+	return &hooks{onStart: onStart, onStop: onStop}
+}
+
+func (h *hooks) Setup() error {
+	return nil
+}
+
+func (h *hooks) Play(ctx context.Context) error {
+	if h.onStart != nil {
+		if err := h.onStart(ctx); err != nil {
+			return err
+		}
+	}
+	<-ctx.Done()
+	if h.onStop == nil {
+		return nil
+	}
+	return h.onStop(context.Background())
+}
+
+func (h *hooks) Clean() {}
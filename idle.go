@@ -0,0 +1,72 @@
+package orchestra
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// IdleAfter makes the stage consider the player done once it has been idle for d, i.e. once it hasn't called `orchestra.Touch` for d.
+// The context given to its Play is then cancelled, and it's treated as if Play returned nil, whatever it actually returns.
+// It's meant for drain jobs, and backfill workers that should exit once there's no more work, for eg,
+//
+//	for msg := range queue.Receive(ctx) {
+//		orchestra.Touch(ctx)
+//		handle(msg)
+//	}
+//
+// A player can also declare itself idle right away, using `orchestra.Idle`.
+func IdleAfter(d time.Duration) Option {
+	return func(e *entry) {
+		e.idleAfter = d
+	}
+}
+
+// errIdle is the cause the context of a player is cancelled with once it's idle
+var errIdle = errors.New("idle")
+
+// idleKey is the key of the idler of a player in the context given to its Play
+type idleKey struct{}
+
+// idler keeps track of when the player last did some work
+type idler struct {
+	last   atomic.Int64 // in unix nanoseconds
+	cancel context.CancelCauseFunc
+}
+
+// Touch tells the stage that the player the context was given to is doing some work, so it isn't idle, see `orchestra.IdleAfter`.
+// It does nothing if the player wasn't added with `orchestra.IdleAfter`
+func Touch(ctx context.Context) {
+	if i, ok := ctx.Value(idleKey{}).(*idler); ok {
+		i.last.Store(time.Now().UnixNano())
+	}
+}
+
+// Idle tells the stage that the player the context was given to is out of work, so it's done, see `orchestra.IdleAfter`.
+// It does nothing if the player wasn't added with `orchestra.IdleAfter`
+func Idle(ctx context.Context) {
+	if i, ok := ctx.Value(idleKey{}).(*idler); ok {
+		i.cancel(errIdle)
+	}
+}
+
+// watch cancels the context of the player with errIdle, once it has been idle for d. It returns once ctx is done
+func (i *idler) watch(ctx context.Context, d time.Duration) {
+	i.last.Store(time.Now().UnixNano())
+	t := time.NewTimer(d)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-t.C:
+			idle := now.Sub(time.Unix(0, i.last.Load()))
+			if idle >= d {
+				i.cancel(errIdle)
+				return
+			}
+			t.Reset(d - idle)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package orchestra
+
+import (
+	"context"
+	"time"
+)
+
+// Init marks the player as a run-once init player, for eg, a database migration.
+// Init players are played one at a time, in the order they were setup, and each of them has to return before the next one Plays.
+// The rest of the players Play only after all of the init players have returned successfully,
+// if any of them fails, the rest of the stage isn't played at all, and the error is returned in the `ErrPlay` of the stage, under the name of the init player.
+// If timeout is non-zero, it's the same as `orchestra.Timeout`.
+func Init(timeout time.Duration) Option {
+	return func(e *entry) {
+		e.init = true
+		if timeout > 0 {
+			e.timeout = timeout
+		}
+	}
+}
+
+// inits splits the players into the init players, and the rest, keeping their order
+func inits(players []*entry) (once, rest []*entry) {
+	for _, it := range players {
+		if it.init {
+			once = append(once, it)
+		} else {
+			rest = append(rest, it)
+		}
+	}
+	return once, rest
+}
+
+// playInits plays the init players to completion, one at a time, it returns the error of the first one that fails
+func (s *Stage) playInits(ctx context.Context, players []*entry) *ErrPlay {
+	for _, it := range players {
+		err := s.play(ctx, ctx, it)
+		if err == nil {
+			continue
+		}
+		e := &ErrPlay{
+			Players:   map[string]error{it.name: err},
+			Durations: make(map[string]time.Duration),
+		}
+		s.mu.Lock()
+		st := it.status
+		s.mu.Unlock()
+		if !st.started.IsZero() && !st.stopped.IsZero() {
+			e.Durations[it.name] = st.stopped.Sub(st.started)
+		}
+		return e
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package orchestra
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"runtime/pprof"
+	"time"
+)
+
+// InspectOnSignal makes the stage write a diagnostic report to w (or os.Stderr if it's nil) every time the process receives SIGUSR1 while the stage is playing,
+// so a running stage can be inspected from the command line, like with gops, even if no admin endpoint was configured ahead of time:
+//
+//	kill -USR1 <pid>
+//
+// The report is (*Stage).Dump, followed by the stacks of all goroutines. Unlike SIGQUIT, the process keeps running.
+// It does nothing on platforms without SIGUSR1, like windows.
+func InspectOnSignal(w io.Writer) StageOption {
+	return func(s *Stage) {
+		if w == nil {
+			w = os.Stderr
+		}
+		s.inspect = w
+	}
+}
+
+// inspection writes the diagnostic report of the stage to w, see `orchestra.InspectOnSignal`
+func (s *Stage) inspection(w io.Writer) {
+	fmt.Fprintf(w, "=== orchestra inspection at %s, pid %d\n", time.Now().Format(time.RFC3339Nano), os.Getpid())
+	s.Dump(w)
+	fmt.Fprintf(w, "=== goroutines\n")
+	pprof.Lookup("goroutine").WriteTo(w, 2)
+	fmt.Fprintf(w, "=== end of inspection\n")
+}
+
+// inspectOnSignal writes the diagnostic report of the stage on every signal, until ctx is done, if the stage is configured to
+func (s *Stage) inspectOnSignal(ctx context.Context) {
+	if s.inspect == nil {
+		return
+	}
+	sigs, stop := inspectSignals()
+	if sigs == nil {
+		return
+	}
+	go func() {
+		defer stop()
+		for {
+			select {
+			case <-sigs:
+				s.inspection(s.inspect)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
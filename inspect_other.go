@@ -0,0 +1,10 @@
+//go:build !unix
+
+package orchestra
+
+import "os"
+
+// inspectSignals returns nil, as there's no SIGUSR1 on this platform, see `orchestra.InspectOnSignal`
+func inspectSignals() (<-chan os.Signal, func()) {
+	return nil, func() {}
+}
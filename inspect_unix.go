@@ -0,0 +1,16 @@
+//go:build unix
+
+package orchestra
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// inspectSignals returns the signals that ask for an inspection, see `orchestra.InspectOnSignal`
+func inspectSignals() (<-chan os.Signal, func()) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGUSR1)
+	return ch, func() { signal.Stop(ch) }
+}
@@ -0,0 +1,64 @@
+package orchestra
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Event is a change in the life cycle of a player
+type Event struct {
+	Time   time.Time
+	Player string
+	Group  string // the group of the player, see `orchestra.Group`
+	State  State  // the state the player moved to
+	Phase  Phase  // the phase the player was in when it moved
+	Err    error  // the error that caused the move, if any
+}
+
+// MarshalJSON encodes the event as a flat object, with the state, phase, and error as strings
+func (e Event) MarshalJSON() ([]byte, error) {
+	v := struct {
+		Time   time.Time `json:"time"`
+		Player string    `json:"player"`
+		Group  string    `json:"group,omitempty"`
+		State  string    `json:"state"`
+		Phase  string    `json:"phase"`
+		Err    string    `json:"error,omitempty"`
+	}{
+		Time:   e.Time,
+		Player: e.Player,
+		Group:  e.Group,
+		State:  e.State.String(),
+		Phase:  e.Phase.String(),
+	}
+	if e.Err != nil {
+		v.Err = e.Err.Error()
+	}
+	return json.Marshal(v)
+}
+
+// journal writes events to a writer as JSON lines
+type journal struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// Journal makes the stage write every life cycle event of its players to w, as a line of JSON, for eg,
+//
+//	{"time":"2022-01-02T15:04:05Z","player":"api","state":"failed","phase":"play","error":"listener closed"}
+//
+// giving an audit trail of what started, stopped, and why, regardless of how (or if) the application logs.
+// Writes are serialized, and errors while writing are ignored.
+func Journal(w io.Writer) StageOption {
+	return func(s *Stage) {
+		s.journal = &journal{enc: json.NewEncoder(w)}
+	}
+}
+
+func (j *journal) write(e Event) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.enc.Encode(e)
+}
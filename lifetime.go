@@ -0,0 +1,80 @@
+package orchestra
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// lifetime is the max lifetime of a player, see `orchestra.MaxLifetime`
+type lifetime struct {
+	max    time.Duration // zero means the player lives forever
+	jitter time.Duration
+}
+
+// next returns how long the player should live this time around
+func (l lifetime) next() time.Duration {
+	if l.jitter <= 0 {
+		return l.max
+	}
+	return l.max + time.Duration(rand.Int63n(int64(l.jitter)))
+}
+
+// MaxLifetime makes the stage recycle the player after it has been playing for d, plus a random duration of up to jitter.
+// To recycle a player, the context given to its Play is cancelled, and once Play returns, it is cleaned, setup again, and played again, like `orchestra.Restart`,
+// except that it isn't counted as a restart, and the error returned by Play is discarded.
+// It's useful for mitigating slow leaks, or forcing periodic reconnects.
+// The stage recycles at most one player at a time, a player that outlives its lifetime keeps playing until the one being recycled before it has been setup again,
+// so the replicas of a pool, for eg, are never all down at once. The jitter spreads them out further.
+// If the player fails to setup again, it's handled as any other failure.
+func MaxLifetime(d, jitter time.Duration) Option {
+	return func(e *entry) {
+		e.lifetime = lifetime{max: d, jitter: jitter}
+	}
+}
+
+// errExpired is returned by (*Stage).playOnce when the player was stopped because it outlived its lifetime
+var errExpired = errors.New("expired")
+
+// expire cancels the current Play of the player with errExpired once it has outlived its lifetime (see (*Stage).playerContext), and it's its turn to be recycled.
+// ctx is the context given to the Play. The returned func stops the countdown, it must be called once the Play returns,
+// and reports whether the player was cancelled, in which case the caller holds the recycling token, and must release it, see (*Stage).recycle
+func (s *Stage) expire(ctx context.Context, it *entry) (stop func() (recycling bool)) {
+	if it.lifetime.max <= 0 {
+		return func() bool { return false }
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	recycling := false
+	t := time.AfterFunc(it.lifetime.next(), func() {
+		defer close(done)
+		if s.recycling.acquire(ctx, 1) != nil {
+			return // it returned before its turn came
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if it.cancel == nil || ctx.Err() != nil {
+			s.recycling.release(1)
+			return
+		}
+		recycling = true
+		it.cancel(errExpired)
+	})
+	return func() bool {
+		cancel()
+		if t.Stop() {
+			return false // it never outlived its lifetime
+		}
+		<-done
+		return recycling
+	}
+}
+
+// recycle sets the player up again once it has outlived its lifetime, releasing the recycling token taken by (*Stage).expire once it's done.
+// it returns errRestarted if the player is ready to be played again, otherwise the error that prevented it
+func (s *Stage) recycle(it *entry) error {
+	defer s.recycling.release(1)
+	s.transition(it, StateRestarting, PhasePlay, nil)
+	return s.reset(it)
+}
@@ -0,0 +1,285 @@
+package orchestra
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+// entry is a player along with everything the stage knows about it
+type entry struct {
+	name   string
+	player Player
+	nested *Stage       // the player, if it's a stage
+	after  []string     // names of the players this player depends on
+	uses   []Dependency // outputs this player receives in its Setup
+
+	latch   chan struct{}   // closed by (*Stage).Release, nil if the player isn't latched
+	release sync.Once       // guards the closing of latch
+	gate    <-chan struct{} // the player plays only after this fires, nil if there's no gate
+
+	timeout       time.Duration // the player's Play is cancelled after this long, zero means no timeout
+	warmupTimeout time.Duration // see `orchestra.WarmupTimeout`
+	window        Schedule      // the player plays only within the windows of this schedule, nil if it can play any time
+	restart       restart       // see `orchestra.Restart`
+	lifetime      lifetime      // see `orchestra.MaxLifetime`
+	broken        bool          // the player failed to setup again after a restart, so it mustn't be cleaned
+	handoff       []byte        // the state exported by the player before its last restart, see `orchestra.Resumer`
+	health        health        // see `orchestra.Hysteresis`
+	scratch       bool          // see `orchestra.Scratch`
+	idleAfter     time.Duration // see `orchestra.IdleAfter`
+	lowPriority   bool          // see `orchestra.LowPriority`
+	critical      bool          // see `orchestra.CriticalClean`
+	quarantine    int           // see `orchestra.Quarantine`
+	dir           string        // the scratch directory of the player, empty if it doesn't have one
+
+	init        bool // see `orchestra.Init`
+	stopOrder   int  // see `orchestra.StopOrder`
+	nonCritical bool // see `orchestra.NonCritical`
+	optional    bool // see `orchestra.Optional`
+
+	group     string         // see `orchestra.Group`
+	exclusive string         // the mutual exclusion group of the player, empty if it's not in one
+	weights   map[string]int // how much of each resource the player needs to Play
+
+	cancel   context.CancelCauseFunc // cancels the Play of the player, nil if it isn't playing. It's guarded by (*Stage).mu
+	returned chan struct{}           // closed once the current Play of the player returns, nil if it isn't playing. It's guarded by (*Stage).mu
+	status   status
+}
+
+// Option configures how a player is handled by the stage, it is passed to (*Stage).Add
+type Option func(*entry)
+
+// After makes the player depend on the named players.
+// The player is setup only after all of the named players have been setup successfully,
+// and it is cleaned before any of them.
+func After(names ...string) Option {
+	return func(e *entry) {
+		e.after = append(e.after, names...)
+	}
+}
+
+// Uses makes the player depend on the players producing the given outputs (see `orchestra.Output`).
+// It implies `orchestra.After` for every producer, and the stage makes sure that every output
+// has been set before the player's Setup is called.
+func Uses(deps ...Dependency) Option {
+	return func(e *entry) {
+		for _, d := range deps {
+			e.after = append(e.after, d.producer())
+		}
+		e.uses = append(e.uses, deps...)
+	}
+}
+
+// Latched makes the player wait for (*Stage).Release before it Plays.
+// The player is setup along with everyone else, but its Play is called only once it's released.
+// If the stage is cancelled before the player is released, its Play is never called.
+func Latched() Option {
+	return func(e *entry) {
+		e.latch = make(chan struct{})
+	}
+}
+
+// Gate is like `orchestra.Latched`, except that the player is released when the given channel fires (or is closed)
+func Gate(ch <-chan struct{}) Option {
+	return func(e *entry) {
+		e.gate = ch
+	}
+}
+
+// Timeout cancels the context given to the player's Play after d, without affecting the rest of the stage.
+// If the player is cancelled because of its timeout, its error is reported as `ErrTimeout` in the `ErrPlay` of the stage.
+func Timeout(d time.Duration) Option {
+	return func(e *entry) {
+		e.timeout = d
+	}
+}
+
+// Window makes the player play only within the windows of the given schedule, for eg, `orchestra.Window(orchestra.Daily(2*time.Hour, 5*time.Hour))`
+// The stage calls the player's Play when a window opens, and cancels it when the window closes, then waits for the next one.
+// So Play is called once for every window, and context errors returned when a window closes aren't considered failures.
+func Window(sch Schedule) Option {
+	return func(e *entry) {
+		e.window = sch
+	}
+}
+
+// restart is the restart policy of a player
+type restart struct {
+	max     int // zero means the player is never restarted, negative means it's always restarted
+	backoff time.Duration
+}
+
+// allows reports whether the player can be restarted after it has been restarted n times
+func (r restart) allows(n int) bool {
+	return r.max < 0 || n < r.max
+}
+
+// Restart makes the stage restart the player when its Play returns an error, at most max times (or forever, if max is negative), waiting backoff before every restart.
+// To restart a player, it is cleaned, setup again, and played again. Failing to setup again also counts as a failure.
+// The errors absorbed by restarts don't make it to the `ErrPlay` of the stage, only the error after which the player can't be restarted does,
+// but they can be collected with `orchestra.DeadLetters`.
+// Players aren't restarted once the stage is cancelled.
+func Restart(max int, backoff time.Duration) Option {
+	return func(e *entry) {
+		e.restart = restart{max: max, backoff: backoff}
+	}
+}
+
+// NonCritical contains the failures of the player, meant for nested stages (or any player) whose failure shouldn't bring the rest of the stage down.
+// If the player fails to setup, the rest of the stage is still setup, and if it fails to play, its error doesn't make it to the `ErrPlay` of the stage.
+// Either way the rest of the players keep running, and the error is recorded (see (*Stage).Contained), and reported as usual.
+// With `orchestra.Restart`, the player is retried as per the policy, including when it fails to setup in the first place.
+// Players that depend on a non-critical player that failed to setup, fail to setup themselves.
+func NonCritical() Option {
+	return func(e *entry) {
+		e.nonCritical = true
+		e.optional = true
+	}
+}
+
+// Optional makes the errors returned by the player's Play never make it to the `ErrPlay` of the stage, so a best-effort background task can't turn a successful run into a failed one.
+// The errors are still recorded (see (*Stage).Contained), logged, and reported as usual.
+// Unlike `orchestra.NonCritical`, the stage still fails to setup if the player does.
+func Optional() Option {
+	return func(e *entry) {
+		e.optional = true
+	}
+}
+
+// Exclusive puts the player in a mutual exclusion group, the stage makes sure that at most one player of the group Plays at a time.
+// The rest of the group is queued, and each of them Plays only once the one before it has returned.
+// It's useful for jobs that touch the same external resource, and must never overlap.
+// If the stage is cancelled while a player is queued, its Play is never called.
+func Exclusive(group string) Option {
+	return func(e *entry) {
+		e.exclusive = group
+	}
+}
+
+// Weight makes the player take n out of the capacity of the named resource (see `orchestra.Resource`) while it Plays.
+// The stage delays the Play of the player until enough of the resource is available, so the capacity is never exceeded.
+// A player can be weighed against any number of resources, and it Plays only once it has acquired all of them.
+func Weight(resource string, n int) Option {
+	return func(e *entry) {
+		if e.weights == nil {
+			e.weights = make(map[string]int)
+		}
+		e.weights[resource] += n
+	}
+}
+
+// resources returns the names of the resources the player needs, in the order they should be acquired in
+func (e *entry) resources() []string {
+	names := make([]string, 0, len(e.weights))
+	for name := range e.weights {
+		names = append(names, name)
+	}
+	sort.Strings(names) // acquiring in the same order everywhere avoids deadlocks
+	return names
+}
+
+// wait blocks until the player is released (if it's latched or gated), and reports whether it was released before ctx got cancelled
+func (e *entry) wait(ctx context.Context) bool {
+	if e.latch == nil && e.gate == nil {
+		return true
+	}
+	// a nil channel blocks forever, so the one that's not configured never fires
+	select {
+	case <-e.latch:
+		return true
+	case <-e.gate:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// StageOption configures a stage, it is passed to `orchestra.NewStage`
+type StageOption func(*Stage)
+
+// Sequential makes the stage play its players one at a time, in the order they were setup,
+// each player's Play has to return before the next one's is called.
+// It's meant for debugging, for eg, to bisect which player corrupts shared state, or deadlocks.
+func Sequential() StageOption {
+	return func(s *Stage) {
+		s.sequential = true
+	}
+}
+
+// ErrorHandler is called with every error returned by a player, see `orchestra.OnError`
+type ErrorHandler func(name string, phase Phase, err error)
+
+// OnError makes the stage call h with every error returned by its players, as soon as it's returned, before it is accumulated into `ErrSetup` or `ErrPlay`.
+// It's useful for shipping errors to an error tracker, or alerting in real time.
+// Note: h may be called concurrently from multiple players' goroutines
+func OnError(h ErrorHandler) StageOption {
+	return func(s *Stage) {
+		s.onError = h
+	}
+}
+
+// Resource declares a named resource pool with the given capacity, for eg, `orchestra.Resource("db-connections", 20)`.
+// Players declare how much of it they need using `orchestra.Weight`.
+func Resource(name string, capacity int) StageOption {
+	return func(s *Stage) {
+		if s.resources == nil {
+			s.resources = make(map[string]*semaphore)
+		}
+		s.resources[name] = newSemaphore(capacity)
+	}
+}
+
+// Logger makes the stage log to l, the stage only logs what isn't surfaced otherwise, for eg, the errors of optional players
+func Logger(l *slog.Logger) StageOption {
+	return func(s *Stage) {
+		s.logger = l
+	}
+}
+
+// log logs to the logger of the stage, if it has one
+func (s *Stage) log(level slog.Level, msg string, args ...any) {
+	if s.logger == nil {
+		return
+	}
+	if s.path != "" {
+		args = append(args, "stage", s.path)
+	}
+	s.logger.Log(context.Background(), level, msg, args...)
+}
+
+// Mode is how a stage without any players plays, see `orchestra.WhenEmpty`
+type Mode int
+
+const (
+	ModeBatch   Mode = iota // Play returns as soon as it's called, as there's nothing to wait for
+	ModeService             // Play blocks until its context is cancelled, as a service with nothing to do is still a service
+)
+
+func (m Mode) String() string {
+	switch m {
+	case ModeBatch:
+		return "batch"
+	case ModeService:
+		return "service"
+	}
+	return fmt.Sprintf("Mode(%d)", int(m))
+}
+
+// WhenEmpty sets how the stage plays if it has no players, it's `orchestra.ModeBatch` by default.
+// Empty nested stages are marked as such in (*Stage).Dump, so they can be told apart from the ones that finished instantly.
+func WhenEmpty(m Mode) StageOption {
+	return func(s *Stage) {
+		s.whenEmpty = m
+	}
+}
+
+// empty reports whether the stage has no players
+func (s *Stage) empty() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.order) == 0
+}
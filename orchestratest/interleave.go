@@ -0,0 +1,119 @@
+// Package orchestratest provides utilities for testing stages, and the players on them
+package orchestratest
+
+import (
+	"context"
+	"math/rand"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/keogami/orchestra"
+)
+
+// Config configures `orchestratest.Interleave`
+type Config struct {
+	Runs      int           // the number of times the stage is run
+	Seed      int64         // the seed of the first run, every run after it uses the next seed
+	MaxJitter time.Duration // the upper bound of the delays injected around Setup, Play, and Clean
+	Duration  time.Duration // how long each run plays before it is cancelled
+}
+
+// Interleave runs a stage built by build, cfg.Runs times, with the scheduling of the players perturbed differently in every run,
+// by injecting random delays and yields around Setup, Play and Clean.
+// It fails t if the stage fails to setup, or the life cycle of any player is broken in any of the runs (see `orchestra.Checked` for the rules).
+// The seed of the run that failed is reported, so that it can be reproduced.
+func Interleave(t testing.TB, cfg Config, build func() *orchestra.Stage) {
+	t.Helper()
+	for run := 0; run < cfg.Runs; run++ {
+		seed := cfg.Seed + int64(run)
+		r := &perturber{rand: rand.New(rand.NewSource(seed)), max: cfg.MaxJitter}
+		var violations []orchestra.Violation
+		var mu sync.Mutex
+		report := func(v orchestra.Violation) {
+			mu.Lock()
+			defer mu.Unlock()
+			violations = append(violations, v)
+		}
+
+		s := build()
+		s.Wrap(orchestra.Checked(report))
+		s.Wrap(func(name string, p orchestra.Player) orchestra.Player {
+			return &interleaved{player: p, perturb: r.perturb}
+		})
+		if err := s.Setup(); err != nil {
+			t.Errorf("run %d (seed %d): %s", run, seed, err)
+		} else {
+			ctx, cancel := context.WithTimeout(context.Background(), cfg.Duration)
+			s.Play(ctx)
+			cancel()
+			s.Clean()
+		}
+
+		for _, v := range violations {
+			t.Errorf("run %d (seed %d): %s", run, seed, v)
+		}
+	}
+}
+
+// perturber injects random delays and yields
+type perturber struct {
+	mu   sync.Mutex
+	rand *rand.Rand
+	max  time.Duration
+}
+
+func (p *perturber) perturb() {
+	p.mu.Lock()
+	yields := p.rand.Intn(4)
+	var d time.Duration
+	if p.max > 0 {
+		d = time.Duration(p.rand.Int63n(int64(p.max)))
+	}
+	p.mu.Unlock()
+	for i := 0; i < yields; i++ {
+		runtime.Gosched()
+	}
+	time.Sleep(d)
+}
+
+// interleaved is a player with perturbations injected around its life cycle
+type interleaved struct {
+	player  orchestra.Player
+	perturb func()
+}
+
+func (p *interleaved) Setup() error {
+	p.perturb()
+	err := p.player.Setup()
+	p.perturb()
+	return err
+}
+
+func (p *interleaved) Play(ctx context.Context) error {
+	p.perturb()
+	err := p.player.Play(ctx)
+	p.perturb()
+	return err
+}
+
+func (p *interleaved) Clean() {
+	p.perturb()
+	p.player.Clean()
+	p.perturb()
+}
+
+func (p *interleaved) CleanContext(ctx context.Context) {
+	p.perturb()
+	if c, ok := p.player.(orchestra.ContextCleaner); ok {
+		c.CleanContext(ctx)
+	} else {
+		p.player.Clean()
+	}
+	p.perturb()
+}
+
+func (p *interleaved) Unwrap() orchestra.Player {
+	return p.player
+}
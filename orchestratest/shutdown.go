@@ -0,0 +1,89 @@
+package orchestratest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/keogami/orchestra"
+)
+
+// ShutsDownWithin sets the stage up, plays it for the given duration, cancels it, and fails t for every player that doesn't return from its Play within the bound,
+// reporting the violators by name, along with how long they overran. It's meant to turn "does my service actually shut down cleanly?" into a single test:
+//
+//	func TestShutdown(t *testing.T) {
+//		orchestratest.ShutsDownWithin(t, buildStage(), time.Second, 5*time.Second)
+//	}
+//
+// The stage is cleaned if every player returns. If some don't, they're waited on for another bound, so that their overrun can be reported,
+// and are then left running, without cleaning the stage.
+// Nested stages are measured as a whole, use `orchestra.Sequential` or test them on their own to tell their players apart.
+func ShutsDownWithin(t testing.TB, s *orchestra.Stage, playFor, bound time.Duration) {
+	t.Helper()
+	sub := s.Subscribe(1024, orchestra.DropOldest) // blocking would hold the players up, and inflate what's being measured
+	var mu sync.Mutex
+	returned := make(map[string]time.Time) // when the players returned from Play
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		for e := range sub.C {
+			if e.Phase != orchestra.PhasePlay || (e.State != orchestra.StateDone && e.State != orchestra.StateFailed) {
+				continue
+			}
+			mu.Lock()
+			returned[e.Player] = e.Time
+			mu.Unlock()
+		}
+	}()
+
+	if err := s.Setup(); err != nil {
+		sub.Close()
+		t.Fatalf("the stage failed to setup: %s", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	played := make(chan struct{})
+	go func() {
+		defer close(played)
+		s.Play(ctx)
+	}()
+
+	time.Sleep(playFor)
+	mu.Lock()
+	for name := range returned {
+		delete(returned, name) // only the returns after the cancellation count
+	}
+	mu.Unlock()
+	cancelled := time.Now()
+	cancel()
+
+	finished := true
+	select {
+	case <-played:
+	case <-time.After(2 * bound):
+		finished = false
+	}
+	sub.Close()
+	<-drained
+	if n := sub.Dropped(); n > 0 {
+		t.Logf("%d events were dropped, so the players they were about may not have been measured", n)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, st := range s.Status() {
+		at, ok := returned[st.Player]
+		switch {
+		case ok && at.Sub(cancelled) > bound:
+			took := at.Sub(cancelled)
+			t.Errorf("%s returned %s after the stage was cancelled, overrunning the bound of %s by %s", st.Player, took, bound, took-bound)
+		case !ok && !finished && (st.State == orchestra.StatePlaying || st.State == orchestra.StateWaiting || st.State == orchestra.StateRestarting):
+			// once Play has returned, so has every player, including the ones that returned without being played, for eg, latched ones that were never released
+			t.Errorf("%s hasn't returned %s after the stage was cancelled, overrunning the bound of %s by more than %s", st.Player, 2*bound, bound, bound)
+		}
+	}
+	if finished {
+		s.Clean()
+	}
+}
@@ -0,0 +1,63 @@
+package orchestratest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/keogami/orchestra"
+)
+
+// recorder is a testing.TB that records the failures, instead of failing the test
+type recorder struct {
+	testing.TB
+	errs []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errs = append(r.errs, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatalf(format string, args ...any) {
+	r.Errorf(format, args...)
+	r.TB.FailNow()
+}
+
+func blocking(ctx context.Context) error {
+	<-ctx.Done()
+	return nil
+}
+
+func TestShutsDownWithin(t *testing.T) {
+	s := orchestra.NewStage()
+	s.Add("server", orchestra.SimplePlayer(blocking))
+	s.Add("latched", orchestra.SimplePlayer(blocking), orchestra.Latched())
+	s.Add("queued", orchestra.SimplePlayer(blocking), orchestra.Exclusive("db"))
+	s.Add("also-queued", orchestra.SimplePlayer(blocking), orchestra.Exclusive("db"))
+
+	r := &recorder{TB: t}
+	ShutsDownWithin(r, s, 10*time.Millisecond, 50*time.Millisecond)
+	if len(r.errs) != 0 {
+		t.Errorf("expected no failures, got %q", r.errs)
+	}
+}
+
+func TestShutsDownWithinOverrun(t *testing.T) {
+	s := orchestra.NewStage()
+	s.Add("server", orchestra.SimplePlayer(blocking))
+	s.Add("slow", orchestra.SimplePlayer(func(ctx context.Context) error {
+		<-ctx.Done()
+		time.Sleep(150 * time.Millisecond)
+		return nil
+	}))
+
+	r := &recorder{TB: t}
+	ShutsDownWithin(r, s, 10*time.Millisecond, 100*time.Millisecond)
+	if len(r.errs) != 1 || !strings.HasPrefix(r.errs[0], "slow returned") {
+		t.Errorf("expected slow to overrun, got %q", r.errs)
+	}
+}
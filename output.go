@@ -0,0 +1,64 @@
+package orchestra
+
+import "sync/atomic"
+
+// Dependency is something a player can be made to depend on using `orchestra.Uses`.
+// It is implemented by `*orchestra.Output`
+type Dependency interface {
+	producer() string
+	produced() bool
+	unset() // forgets that the output was set, before its producer is setup again
+}
+
+// Output is a typed value produced by the Setup of one player and received by the Setup of the players that use it,
+// for eg, a bound net.Listener, or a connected client.
+// The type of the value is checked at compile time, so there's no need for global variables to hand things over between players.
+//
+//	ln := orchestra.NewOutput[net.Listener]("listener")
+//	stage.Add("listener", &listener{out: ln})
+//	stage.Add("server", &server{ln: ln}, orchestra.Uses(ln))
+type Output[T any] struct {
+	from  string
+	value atomic.Pointer[T] // read by the consumers, while the producer may be setup again after a restart
+	set   atomic.Bool
+}
+
+// NewOutput creates an output that is produced by the player named `from`
+func NewOutput[T any](from string) *Output[T] {
+	return &Output[T]{from: from}
+}
+
+// Set sets the value of the output, it is meant to be called by the producer from within its Setup.
+// The output has to be set again every time the producer is setup, for eg, after a restart, or it's considered missing
+func (o *Output[T]) Set(v T) {
+	o.value.Store(&v)
+	o.set.Store(true)
+}
+
+// Value returns the value set by the producer.
+// It is safe to call from the Setup (or later) of any player added with `orchestra.Uses(o)`
+func (o *Output[T]) Value() T {
+	if v := o.value.Load(); v != nil {
+		return *v
+	}
+	var zero T
+	return zero
+}
+
+// From returns the name of the player that produces this output
+func (o *Output[T]) From() string {
+	return o.from
+}
+
+func (o *Output[T]) producer() string {
+	return o.from
+}
+
+func (o *Output[T]) produced() bool {
+	return o.set.Load()
+}
+
+// unset keeps the value, so the consumers that are still playing aren't left with the zero value
+func (o *Output[T]) unset() {
+	o.set.Store(false)
+}
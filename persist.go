@@ -0,0 +1,101 @@
+package orchestra
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"sort"
+)
+
+// Mutations are the changes made to a stage while it runs, that outlive the process, see `orchestra.Persist`
+type Mutations struct {
+	Paused []string `json:"paused,omitempty"` // the groups that are paused, see (*Stage).PauseGroup
+}
+
+// Store is where a stage persists its mutations, see `orchestra.Persist`
+type Store interface {
+	Load() (Mutations, error) // returns the zero value if nothing has been saved yet
+	Save(m Mutations) error
+}
+
+// Persist makes the stage save its mutations to store whenever they change, and reapply them every time it's setup,
+// so operational changes, like pausing the reindexer, survive a deploy instead of silently reverting.
+// Only reversible mutations are persisted, i.e. groups paused with (*Stage).PauseGroup (and not resumed), but not the ones stopped with (*Stage).StopGroup.
+// Errors while accessing the store are logged (see `orchestra.Logger`), and otherwise ignored.
+func Persist(store Store) StageOption {
+	return func(s *Stage) {
+		s.store = store
+	}
+}
+
+// restore reapplies the mutations saved in the store of the stage, if it has one
+func (s *Stage) restore() {
+	if s.store == nil {
+		return
+	}
+	m, err := s.store.Load()
+	if err != nil {
+		s.log(slog.LevelWarn, "failed to load mutations", "error", err)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, name := range m.Paused {
+		g := s.groupOf(name)
+		if g.resumed == nil {
+			g.resumed = make(chan struct{})
+		}
+	}
+}
+
+// persist saves the mutations of the stage to its store, if it has one
+func (s *Stage) persist() {
+	if s.store == nil {
+		return
+	}
+	var m Mutations
+	s.mu.Lock()
+	for name, g := range s.groups {
+		if g.resumed != nil {
+			m.Paused = append(m.Paused, name)
+		}
+	}
+	s.mu.Unlock()
+	sort.Strings(m.Paused)
+	if err := s.store.Save(m); err != nil {
+		s.log(slog.LevelWarn, "failed to save mutations", "error", err)
+	}
+}
+
+// fileStore is the store returned by `orchestra.FileStore`
+type fileStore struct {
+	path string
+}
+
+// FileStore returns a store that keeps the mutations in the file at path, as JSON. The file is replaced atomically on every save
+func FileStore(path string) Store {
+	return fileStore{path: path}
+}
+
+func (f fileStore) Load() (Mutations, error) {
+	var m Mutations
+	b, err := os.ReadFile(f.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return m, err
+	}
+	err = json.Unmarshal(b, &m)
+	return m, err
+}
+
+func (f fileStore) Save(m Mutations) error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(f.path, b)
+}
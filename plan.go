@@ -0,0 +1,144 @@
+package orchestra
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Plan is what the stage would do, without doing any of it, see (*Stage).Plan
+type Plan struct {
+	Setup []PlanStep    // the players in the order they'd be setup
+	Init  []string      // the init players, in the order they'd be played before the rest, see `orchestra.Init`
+	Stop  [][]string    // the waves the players would be stopped in, see `orchestra.StopOrder`
+	Clean [][]string    // the players that would be cleaned together, wave by wave, the dependents always before their dependencies
+	Path  []string      // the chain of dependencies that's expected to take the longest to setup
+	Took  time.Duration // how long the setup is expected to take, i.e. the sum of the expected durations, as the players are setup one at a time
+}
+
+// PlanStep is a single player of a `orchestra.Plan`
+type PlanStep struct {
+	Player   string
+	After    []string      // the players it depends on
+	Expected time.Duration // how long its setup is expected to take, see (*Stage).Plan
+	Nested   *Plan         // the plan of the player, if it's a nested stage
+}
+
+// Plan computes the order in which the stage would setup, play, stop, and clean its players, without calling any of them,
+// so changes to the topology of a large stage can be reviewed before they're run.
+//
+// The expected durations are estimated from how long each player took to setup and warm up the last time (see (*Stage).StartupReport),
+// falling back to its `orchestra.WarmupTimeout` (or zero) if it hasn't been setup yet.
+// If the dependencies are broken, the error is the one (*Stage).Setup would return.
+func (s *Stage) Plan() (Plan, error) {
+	s.mu.Lock()
+	sorted, err := s.sorted()
+	s.mu.Unlock()
+	if err != nil {
+		return Plan{}, err
+	}
+
+	var p Plan
+	level := make(map[string]int, len(sorted))   // how deep in the dependencies the player is
+	took := make(map[string]time.Duration)       // how long the longest chain ending at the player is expected to take
+	prev := make(map[string]string, len(sorted)) // the dependency on that chain
+	var longest time.Duration                    // how long the longest chain is expected to take
+	maxLevel := 0
+	for _, it := range sorted {
+		step := PlanStep{Player: it.name, After: it.after, Expected: s.expected(it)}
+		if it.nested != nil {
+			if nested, err := it.nested.Plan(); err == nil {
+				step.Nested = &nested
+				step.Expected = max(step.Expected, nested.Took)
+			}
+		}
+		p.Setup = append(p.Setup, step)
+		if it.init {
+			p.Init = append(p.Init, it.name)
+		}
+
+		for _, dep := range it.after {
+			level[it.name] = max(level[it.name], level[dep]+1)
+			if _, ok := prev[it.name]; !ok || took[dep] > took[prev[it.name]] {
+				prev[it.name] = dep
+			}
+		}
+		took[it.name] = step.Expected + took[prev[it.name]]
+		maxLevel = max(maxLevel, level[it.name])
+		p.Took += step.Expected
+		if took[it.name] >= longest {
+			longest = took[it.name]
+			p.Path = p.Path[:0]
+			for name := it.name; name != ""; name = prev[name] {
+				p.Path = append([]string{name}, p.Path...)
+			}
+		}
+	}
+
+	_, rest := inits(sorted)
+	for _, w := range waves(rest, s.waveWeights) {
+		var names []string
+		for _, it := range w.players {
+			names = append(names, it.name)
+		}
+		p.Stop = append(p.Stop, names)
+	}
+
+	p.Clean = make([][]string, maxLevel+1)
+	for _, it := range sorted {
+		// the deepest players have no dependents that are deeper still, so they're cleaned first
+		i := maxLevel - level[it.name]
+		p.Clean[i] = append(p.Clean[i], it.name)
+	}
+	if len(sorted) == 0 {
+		p.Clean = nil
+	}
+	return p, nil
+}
+
+// expected returns how long the setup of the player is expected to take
+func (s *Stage) expected(it *entry) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if it.status.setupTook > 0 {
+		return it.status.setupTook
+	}
+	return it.warmupTimeout
+}
+
+// String describes the plan, in the order things would happen
+func (p Plan) String() string {
+	b := &strings.Builder{}
+	p.write(b, "")
+	return b.String()
+}
+
+func (p Plan) write(b *strings.Builder, indent string) {
+	fmt.Fprintf(b, "%ssetup:\n", indent)
+	for i, step := range p.Setup {
+		fmt.Fprintf(b, "%s  %d. %s", indent, i, step.Player)
+		if len(step.After) > 0 {
+			fmt.Fprintf(b, " after %s", strings.Join(step.After, ","))
+		}
+		if step.Expected > 0 {
+			fmt.Fprintf(b, " (~%s)", step.Expected)
+		}
+		b.WriteString("\n")
+		if step.Nested != nil {
+			step.Nested.write(b, indent+"     ")
+		}
+	}
+	if len(p.Init) > 0 {
+		fmt.Fprintf(b, "%sinit: %s\n", indent, strings.Join(p.Init, " -> "))
+	}
+	fmt.Fprintf(b, "%sstop:\n", indent)
+	for i, w := range p.Stop {
+		fmt.Fprintf(b, "%s  wave %d: %s\n", indent, i, strings.Join(w, ","))
+	}
+	fmt.Fprintf(b, "%sclean:\n", indent)
+	for i, w := range p.Clean {
+		fmt.Fprintf(b, "%s  wave %d: %s\n", indent, i, strings.Join(w, ","))
+	}
+	fmt.Fprintf(b, "%slongest chain: %s\n", indent, strings.Join(p.Path, " -> "))
+	fmt.Fprintf(b, "%sexpected setup: ~%s\n", indent, p.Took)
+}
@@ -0,0 +1,89 @@
+package orchestra
+
+import (
+	"context"
+	"sync"
+)
+
+// LowPriority makes the player yield to the rest of the stage while it's under pressure, see `orchestra.Yield`
+func LowPriority() Option {
+	return func(e *entry) {
+		e.lowPriority = true
+	}
+}
+
+// pressure is how many times the stage has been preempted, and not released yet
+type pressure struct {
+	mu      sync.Mutex
+	n       int
+	cleared chan struct{} // closed once n drops back to zero, nil if there's no pressure
+}
+
+// Preempt puts the stage under pressure, asking its low-priority players (see `orchestra.LowPriority`) to yield until the returned func is called.
+// It can be called any number of times, the pressure clears once every one of them is released. Releasing more than once does nothing.
+// It's meant for simple in-process load shedding, for eg, when a high-priority player is falling behind, or memory is running low.
+func (s *Stage) Preempt() (release func()) {
+	p := &s.pressure
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.n == 0 {
+		p.cleared = make(chan struct{})
+	}
+	p.n++
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			p.n--
+			if p.n == 0 {
+				close(p.cleared)
+				p.cleared = nil
+			}
+		})
+	}
+}
+
+// Preempt is (*Stage).Preempt for the stage the context was given by, so a high-priority player can ask the rest of the stage to yield.
+// It does nothing if the context wasn't given to a player by a stage
+func Preempt(ctx context.Context) (release func()) {
+	p, ok := playerOf(ctx)
+	if !ok {
+		return func() {}
+	}
+	return p.stage.Preempt()
+}
+
+// Yield blocks while the stage is under pressure (see (*Stage).Preempt), if the player the context was given to is `orchestra.LowPriority`.
+// It's meant to be called by the player between units of work, it returns ctx.Err() if ctx is done while it waits, nil otherwise.
+// It returns right away for the rest of the players.
+func Yield(ctx context.Context) error {
+	p, ok := playerOf(ctx)
+	if !ok || !p.entry.lowPriority {
+		return nil
+	}
+	p.stage.pressure.mu.Lock()
+	cleared := p.stage.pressure.cleared
+	p.stage.pressure.mu.Unlock()
+	if cleared == nil {
+		return nil
+	}
+	select {
+	case <-cleared:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Pressured reports whether the stage is under pressure, if the player the context was given to is `orchestra.LowPriority`,
+// so it can slow down instead of stopping outright, unlike `orchestra.Yield`. It's always false for the rest of the players.
+func Pressured(ctx context.Context) bool {
+	p, ok := playerOf(ctx)
+	if !ok || !p.entry.lowPriority {
+		return false
+	}
+	p.stage.pressure.mu.Lock()
+	defer p.stage.pressure.mu.Unlock()
+	return p.stage.pressure.n > 0
+}
@@ -0,0 +1,37 @@
+package orchestra
+
+import "time"
+
+// Preset bundles the given options into one, so a large stage can apply the same set of options to many players consistently, for eg,
+//
+//	critical := orchestra.Preset(orchestra.Restart(-1, time.Second), orchestra.StopOrder(1), orchestra.CriticalClean())
+//	stage.Add("ledger", ledger, critical)
+//	stage.Add("outbox", outbox, critical, orchestra.After("ledger"))
+//
+// The options are applied in order, so the ones given to (*Stage).Add after a preset override the ones in it.
+func Preset(opts ...Option) Option {
+	return func(e *entry) {
+		for _, opt := range opts {
+			opt(e)
+		}
+	}
+}
+
+// ServicePreset is the preset for long-running services: they're always restarted (with a second of backoff), and a single failed health check
+// doesn't make them unhealthy (see `orchestra.Hysteresis`). They're stopped after the rest of the stage, so the others can drain into them.
+func ServicePreset() Option {
+	return Preset(
+		Restart(-1, time.Second),
+		Hysteresis(3),
+		StopOrder(1),
+	)
+}
+
+// JobPreset is the preset for jobs that run to completion: they're given timeout to do so (see `orchestra.Timeout`), and are never restarted.
+// Their errors still fail the stage, combine it with `orchestra.Optional` for best-effort jobs.
+func JobPreset(timeout time.Duration) Option {
+	return Preset(
+		Timeout(timeout),
+		Restart(0, 0),
+	)
+}
@@ -0,0 +1,161 @@
+package orchestra
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// ProcessGroup holds the defaults of a group of external processes supervised by a stage, so they're configured once rather than per command.
+//
+//	g := &orchestra.ProcessGroup{Dir: "/srv/app", Env: []string{"MODE=prod"}, Grace: 10 * time.Second}
+//	stage.Add("indexer", g.Command("indexer", "--watch"))
+//	stage.Add("renderer", g.Command("renderer"), orchestra.Restart(-1, time.Second))
+type ProcessGroup struct {
+	Env    []string      // added to the environment of the processes, on top of the environment of this process
+	Dir    string        // the working directory of the processes, the current one if empty
+	Stdout io.Writer     // where the output of the processes goes, see (*ProcessGroup).Command
+	Stderr io.Writer     // where the errors of the processes go, see (*ProcessGroup).Command
+	Grace  time.Duration // how long a process has to exit after it's interrupted, before it's killed. It's killed right away if zero
+}
+
+// Process is a player that runs an external process, it is created using (*ProcessGroup).Command
+type Process struct {
+	Path string
+	Args []string
+	Env  []string // added to the environment of the process, on top of the group's
+	Dir  string   // overrides the working directory of the group, if not empty
+
+	group  *ProcessGroup
+	logger *slog.Logger // the logger of the stage, nil if it doesn't have one
+	name   string
+}
+
+// Command creates a player that runs the named program with the given arguments, with the defaults of the group.
+// Its Setup makes sure that the program exists, and its Play runs the program, returning once it exits.
+// When the stage is cancelled, the process is interrupted, and killed if it doesn't exit within the grace period of the group.
+// It's killed right away where interrupting a process isn't supported, for eg, on windows.
+// A process that exits because it was interrupted, or killed that way isn't considered failed, but one that exits with a non-zero code is.
+//
+// If the group has no Stdout (or Stderr), and the stage has a logger (see `orchestra.Logger`), every line written by the process is logged,
+// otherwise it goes to the Stdout (or Stderr) of this process.
+// Note: resource limits aren't set by the stage, as there's no portable way to do so, wrap the program (for eg, with prlimit, or systemd-run) if needed
+func (g *ProcessGroup) Command(name string, args ...string) *Process {
+	return &Process{Path: name, Args: args, group: g}
+}
+
+// bind gives the process the logger of the stage, it is called by the stage before Setup
+func (p *Process) bind(s *Stage, it *entry) {
+	p.logger, p.name = s.logger, it.name
+}
+
+func (p *Process) Setup() error {
+	path, err := exec.LookPath(p.Path)
+	if err != nil {
+		return err
+	}
+	p.Path = path
+	return nil
+}
+
+func (p *Process) Play(ctx context.Context) error {
+	cmd := exec.CommandContext(ctx, p.Path, p.Args...)
+	cmd.Env = append(append(os.Environ(), p.group.Env...), p.Env...)
+	cmd.Dir = p.group.Dir
+	if p.Dir != "" {
+		cmd.Dir = p.Dir
+	}
+	if p.group.Grace > 0 {
+		cmd.Cancel = func() error {
+			if err := cmd.Process.Signal(os.Interrupt); err != nil && !errors.Is(err, os.ErrProcessDone) {
+				return cmd.Process.Kill()
+			}
+			return nil
+		}
+		cmd.WaitDelay = p.group.Grace
+	}
+	stdout := p.output(p.group.Stdout, os.Stdout, slog.LevelInfo, "stdout")
+	stderr := p.output(p.group.Stderr, os.Stderr, slog.LevelWarn, "stderr")
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	err := cmd.Run()
+	stdout.Flush()
+	stderr.Flush()
+	if ctx.Err() != nil && stopped(err) {
+		return nil
+	}
+	return err
+}
+
+// stopped reports whether the process exited because it was stopped by the stage, i.e. it was interrupted, or killed when its context was done,
+// which is how it returns ctx.Err() if it exits with 0, or exec.ErrWaitDelay if its grace period ran out
+func stopped(err error) bool {
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		return exit.ExitCode() == -1 // it was terminated by a signal
+	}
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, exec.ErrWaitDelay)
+}
+
+func (p *Process) Clean() {}
+
+// output returns where a stream of the process goes
+func (p *Process) output(w, fallback io.Writer, level slog.Level, stream string) flusher {
+	switch {
+	case w != nil:
+		return nopFlusher{w}
+	case p.logger != nil:
+		return &lines{logger: p.logger.With("player", p.name, "stream", stream), level: level}
+	}
+	return nopFlusher{fallback}
+}
+
+// flusher is a writer that may hold on to what's written, till it's flushed
+type flusher interface {
+	io.Writer
+	Flush()
+}
+
+type nopFlusher struct {
+	io.Writer
+}
+
+func (nopFlusher) Flush() {}
+
+// lines logs everything written to it, a line at a time
+type lines struct {
+	mu     sync.Mutex
+	logger *slog.Logger
+	level  slog.Level
+	buf    []byte
+}
+
+func (l *lines) Write(b []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.buf = append(l.buf, b...)
+	for {
+		i := bytes.IndexByte(l.buf, '\n')
+		if i < 0 {
+			break
+		}
+		l.logger.Log(context.Background(), l.level, string(l.buf[:i]))
+		l.buf = l.buf[i+1:]
+	}
+	return len(b), nil
+}
+
+// Flush logs whatever is left, even if it isn't a whole line
+func (l *lines) Flush() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.buf) > 0 {
+		l.logger.Log(context.Background(), l.level, string(l.buf))
+		l.buf = nil
+	}
+}
@@ -0,0 +1,138 @@
+package orchestra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+)
+
+// ErrPanic is the error a panic in a player is turned into, when the stage recovers it, see `orchestra.Recover`
+type ErrPanic struct {
+	Value interface{} // the value the player panicked with
+	Stack []byte      // the stack of the goroutine that panicked
+}
+
+func (e ErrPanic) Error() string {
+	return fmt.Sprintf("ErrPanic: %v", e.Value)
+}
+
+// Format implements fmt.Formatter, %+v prints the stack along with the message
+func (e ErrPanic) Format(f fmt.State, verb rune) {
+	if verb != 'v' || !f.Flag('+') {
+		compact(f, verb, e.Error())
+		return
+	}
+	fmt.Fprintf(f, "%s\n%s", e.Error(), e.Stack)
+}
+
+// Recover makes the stage recover the panics in the Setup and Play of its players, turning them into an `ErrPanic`,
+// instead of letting them take the whole process down.
+func Recover() StageOption {
+	return func(s *Stage) {
+		s.recover = true
+	}
+}
+
+// recovered turns a panic into an `ErrPanic` in *err, it must be deferred
+func recovered(err *error) {
+	if v := recover(); v != nil {
+		*err = ErrPanic{Value: v, Stack: debug.Stack()}
+	}
+}
+
+// setupPlayer calls the Setup of the player, recovering it if the stage is configured to
+func (s *Stage) setupPlayer(it *entry) error {
+	s.unset(it)
+	if err := it.makeScratch(); err != nil {
+		return err
+	}
+	err := s.setupOnly(it)
+	if err == nil {
+		err = s.warmup(it)
+	}
+	if err != nil {
+		it.removeScratch()
+	}
+	return err
+}
+
+// setupOnly calls the Setup of the player, recovering it if the stage is configured to
+func (s *Stage) setupOnly(it *entry) (err error) {
+	if s.recover {
+		defer recovered(&err)
+	}
+	if b, ok := as[binder](it.player); ok {
+		b.bind(s, it)
+	}
+	return it.player.Setup()
+}
+
+// playPlayer calls the Play of the player, recovering it if the stage is configured to
+func (s *Stage) playPlayer(ctx context.Context, it *entry) (err error) {
+	if s.recover {
+		defer recovered(&err)
+	}
+	return it.player.Play(ctx)
+}
+
+// Failure is everything the stage knows about an error returned by a player, see `orchestra.Reporter`
+type Failure struct {
+	Player   string // the name of the player
+	Path     string // the name of the player, prefixed by the names of the stages it is nested in, for eg, "workers/consumer"
+	Phase    Phase
+	Restarts int    // the number of times the player has been restarted
+	Err      error  // the error returned by the player
+	Stack    []byte // the stack of the player, if it panicked (see `orchestra.Recover`)
+}
+
+// Reporter is called with every failure of the players on a stage, it is meant for crash reporting services (like sentry).
+// Report is called from the goroutines of the players, so it must be safe for concurrent use
+type Reporter interface {
+	Report(Failure)
+}
+
+// ReporterFunc is a function that can be used as a `orchestra.Reporter`
+type ReporterFunc func(Failure)
+
+// Report calls the function
+func (f ReporterFunc) Report(fl Failure) {
+	f(fl)
+}
+
+// Report makes the stage report every failure of its players (including the ones of the players on nested stages) to r
+func Report(r Reporter) StageOption {
+	return func(s *Stage) {
+		s.reporter = r
+	}
+}
+
+// LogReporter is a reference reporter that writes every failure to l, with the stack if there's one
+func LogReporter(l *log.Logger) Reporter {
+	return ReporterFunc(func(f Failure) {
+		l.Printf("orchestra: %s failed to %s (restarts: %d): %v", f.Path, f.Phase, f.Restarts, f.Err)
+		if f.Stack != nil {
+			l.Writer().Write(f.Stack)
+		}
+	})
+}
+
+// failure describes the error returned by the player, the caller must hold the lock
+func (s *Stage) failure(e *entry, phase Phase, err error) Failure {
+	f := Failure{
+		Player:   e.name,
+		Path:     e.name,
+		Phase:    phase,
+		Restarts: e.status.restarts,
+		Err:      err,
+	}
+	if s.path != "" {
+		f.Path = s.path + "/" + e.name
+	}
+	var p ErrPanic
+	if errors.As(err, &p) {
+		f.Stack = p.Stack
+	}
+	return f
+}
@@ -0,0 +1,103 @@
+package orchestra
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Schedule decides when a player is allowed to play, see `orchestra.Window`.
+// Calendars other than the ones provided by this package can be used by implementing it
+type Schedule interface {
+	// Next returns the first window that closes after t, the window is already open if start isn't after t
+	Next(t time.Time) (start, end time.Time)
+}
+
+// daily is a window that opens every day, see `orchestra.Daily`
+type daily struct {
+	from, to time.Duration
+	loc      *time.Location // nil means the location of the time given to Next
+}
+
+// Daily is a schedule with a window every day from `from` to `to`, which are offsets from the midnight of the local time,
+// for eg, `orchestra.Daily(2*time.Hour, 5*time.Hour)` is open from 02:00 to 05:00.
+// If `to` isn't after `from` the window closes on the next day, so `orchestra.Daily(22*time.Hour, 2*time.Hour)` is open overnight.
+// The offsets are wall clock times, so the window opens at 02:00 even on the days the clocks change for daylight saving time,
+// and a window that opens at a time that's skipped that day, opens as soon as the clocks are past it.
+func Daily(from, to time.Duration) Schedule {
+	return daily{from: from, to: to}
+}
+
+// DailyIn is `orchestra.Daily` in the given location (see time.LoadLocation), regardless of the location of the process, for eg,
+//
+//	nyc, err := time.LoadLocation("America/New_York")
+//	stage.Add("report", report, orchestra.Window(orchestra.DailyIn(nyc, 9*time.Hour, 10*time.Hour)))
+func DailyIn(loc *time.Location, from, to time.Duration) Schedule {
+	return daily{from: from, to: to, loc: loc}
+}
+
+func (d daily) Next(t time.Time) (time.Time, time.Time) {
+	loc := d.loc
+	if loc == nil {
+		loc = t.Location()
+	}
+	y, m, day := t.In(loc).Date()
+	overnight := d.to <= d.from
+	// a window that opened yesterday may still be open
+	for i := -1; ; i++ {
+		start := at(y, m, day+i, d.from, loc)
+		end := at(y, m, day+i, d.to, loc)
+		if overnight {
+			end = at(y, m, day+i+1, d.to, loc)
+		}
+		if end.After(t) {
+			return start, end
+		}
+	}
+}
+
+// at returns the wall clock time, offset from the midnight of the given day
+func at(y int, m time.Month, day int, offset time.Duration, loc *time.Location) time.Time {
+	h, min := int(offset/time.Hour), int(offset%time.Hour/time.Minute)
+	return time.Date(y, m, day, h, min, 0, int(offset%time.Minute), loc)
+}
+
+func (d daily) String() string {
+	if d.loc != nil {
+		return fmt.Sprintf("daily(%s-%s %s)", clock(d.from), clock(d.to), d.loc)
+	}
+	return fmt.Sprintf("daily(%s-%s)", clock(d.from), clock(d.to))
+}
+
+// clock formats an offset from midnight as HH:MM
+func clock(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+}
+
+// runAt is the player returned by `orchestra.RunAt`
+type runAt struct {
+	Player
+	at time.Time
+}
+
+// RunAt makes p a one-shot player that Plays at t, instead of as soon as the stage does.
+// It's setup, and cleaned along with the rest of the stage, but its Play waits until t, returning nil without calling the Play of p if the stage is cancelled before then.
+// If t has already passed, p Plays right away.
+func RunAt(t time.Time, p Player) Player {
+	return &runAt{Player: p, at: t}
+}
+
+func (r *runAt) CleanContext(ctx context.Context) {
+	cleanPlayer(ctx, r.Player)
+}
+
+func (r *runAt) Unwrap() Player {
+	return r.Player
+}
+
+func (r *runAt) Play(ctx context.Context) error {
+	if !sleep(ctx, time.Until(r.at)) {
+		return nil
+	}
+	return r.Player.Play(ctx)
+}
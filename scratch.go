@@ -0,0 +1,47 @@
+package orchestra
+
+import (
+	"context"
+	"os"
+	"strings"
+)
+
+// scratchKey is the key of the scratch directory of a player in the context given to its Play
+type scratchKey struct{}
+
+// Scratch makes the stage create a temporary directory for the player right before it's setup, and remove it (along with everything in it) once it's cleaned.
+// The directory is given to the player through the context given to its Play, see `orchestra.ScratchDir`.
+// If the player is restarted, it gets a fresh directory. If the directory can't be created, the player fails to setup.
+func Scratch() Option {
+	return func(e *entry) {
+		e.scratch = true
+	}
+}
+
+// ScratchDir returns the scratch directory of the player the context was given to, or an empty string if it was added without `orchestra.Scratch`
+func ScratchDir(ctx context.Context) string {
+	dir, _ := ctx.Value(scratchKey{}).(string)
+	return dir
+}
+
+// makeScratch creates the scratch directory of the player, if it needs one
+func (it *entry) makeScratch() error {
+	if !it.scratch || it.dir != "" {
+		return nil
+	}
+	dir, err := os.MkdirTemp("", "orchestra-"+strings.ReplaceAll(it.name, string(os.PathSeparator), "-")+"-")
+	if err != nil {
+		return err
+	}
+	it.dir = dir
+	return nil
+}
+
+// removeScratch removes the scratch directory of the player, if it has one
+func (it *entry) removeScratch() {
+	if it.dir == "" {
+		return
+	}
+	os.RemoveAll(it.dir)
+	it.dir = ""
+}
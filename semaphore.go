@@ -0,0 +1,49 @@
+package orchestra
+
+import (
+	"context"
+	"sync"
+)
+
+// semaphore is a weighted semaphore that can be waited on with a context
+type semaphore struct {
+	mu   sync.Mutex
+	size int
+	cur  int
+	wake chan struct{} // closed (and replaced) every time something is released
+}
+
+func newSemaphore(size int) *semaphore {
+	return &semaphore{
+		size: size,
+		wake: make(chan struct{}),
+	}
+}
+
+// acquire blocks until n can be acquired, or ctx is done
+func (s *semaphore) acquire(ctx context.Context, n int) error {
+	for {
+		s.mu.Lock()
+		if s.cur+n <= s.size {
+			s.cur += n
+			s.mu.Unlock()
+			return nil
+		}
+		wake := s.wake
+		s.mu.Unlock()
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// release gives back n, waking up everyone waiting to acquire
+func (s *semaphore) release(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cur -= n
+	close(s.wake)
+	s.wake = make(chan struct{})
+}
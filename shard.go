@@ -0,0 +1,284 @@
+package orchestra
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Rebalancer can be implemented by the replicas of `orchestra.Sharded`, to change their shard assignment without being restarted.
+//
+// When the shards are resized, the replicas that are going away are stopped and cleaned first,
+// then every remaining replica is asked to Revoke its old assignment, and only after all of them have,
+// they're asked to Assign the new one. The new replicas are started at the very end.
+// That way no shard is ever consumed by two replicas at once.
+type Rebalancer interface {
+	Revoke(shard, total int) error // stop consuming the old assignment
+	Assign(shard, total int) error // start consuming the new assignment
+}
+
+// Shards is a player made of replicas of another player, each knowing the shard it's assigned to.
+// It's created using `orchestra.Sharded`
+type Shards struct {
+	factory func(shard, total int) Player
+
+	mu     sync.Mutex
+	total  int
+	resize chan struct{} // notifies Play that total has changed
+
+	replicas []*replica // the replicas that are currently setup
+	exited   chan struct{}
+}
+
+// replica is a single shard of `orchestra.Shards`
+type replica struct {
+	player Player
+	cancel context.CancelFunc
+	done   chan struct{} // closed when Play returns
+	err    error         // the error returned by Play, valid after done is closed
+	seen   bool          // whether Play has noticed that this replica exited
+}
+
+// Sharded creates a player made of n replicas, where each replica is created by calling factory with its shard (0 to n-1),
+// and the total number of shards. It's useful for partitioned consumption.
+// The replicas are named "shard-0", "shard-1", and so on in errors.
+func Sharded(n int, factory func(shard, total int) Player) *Shards {
+	return &Shards{
+		factory: factory,
+		total:   n,
+		resize:  make(chan struct{}, 1),
+		exited:  make(chan struct{}, 1),
+	}
+}
+
+// Resize changes the number of shards to n.
+// If the shards are playing, the replicas are either rebalanced (see `orchestra.Rebalancer`)
+// or gracefully restarted with their new assignment: all the old replicas are cancelled and cleaned before any of the new ones are setup,
+// so no shard is ever consumed twice. The replicas that have already returned are done, so they're kept as they are, and never played again.
+// Resizing to zero stops every replica, but Play keeps waiting for its context to be done, or for the shards to be resized again.
+func (s *Shards) Resize(n int) {
+	s.mu.Lock()
+	s.total = n
+	s.mu.Unlock()
+	select {
+	case s.resize <- struct{}{}:
+	default: // there's already a resize pending, and it'll pick up the new total
+	}
+}
+
+// Total returns the current number of shards
+func (s *Shards) Total() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.total
+}
+
+// Setup creates the replicas and sets them up, if any replica fails to setup, the ones before it are cleaned
+func (s *Shards) Setup() error {
+	n := s.Total()
+	s.replicas = nil
+	for i := 0; i < n; i++ {
+		p := s.factory(i, n)
+		if err := p.Setup(); err != nil {
+			s.Clean()
+			return ErrSetup{Player: shardName(i), Err: err}
+		}
+		s.replicas = append(s.replicas, &replica{player: p})
+	}
+	return nil
+}
+
+// Play plays all the replicas, rebalancing them whenever the shards are resized.
+// It returns once none of the replicas are playing, errors returned by replicas while they are being stopped for a resize are discarded.
+func (s *Shards) Play(ctx context.Context) error {
+	for _, r := range s.replicas {
+		s.start(ctx, r)
+	}
+
+	var err *ErrPlay
+	scaled := false // whether the shards have been resized while playing
+	for s.playing() || (scaled && len(s.replicas) == 0 && ctx.Err() == nil) {
+		var idle <-chan struct{} // there's no replica to notice that ctx is done, if they've all been resized away
+		if len(s.replicas) == 0 {
+			idle = ctx.Done()
+		}
+		select {
+		case <-idle:
+		case <-s.exited:
+		case <-s.resize:
+			if ctx.Err() != nil {
+				continue // the replicas are going down anyway, there's no point in rebalancing
+			}
+			if e := s.rebalance(ctx, s.Total()); e != nil {
+				s.stop(s.replicas)
+				s.Clean()
+				s.replicas = nil
+				return e
+			}
+			scaled = true
+		}
+		for i, r := range s.replicas {
+			select {
+			case <-r.done:
+			default:
+				continue
+			}
+			if r.seen {
+				continue
+			}
+			r.seen = true
+			if r.err != nil {
+				if err == nil {
+					err = &ErrPlay{Players: make(map[string]error)}
+				}
+				err.Players[shardName(i)] = r.err
+			}
+		}
+	}
+	if err == nil {
+		return nil
+	}
+	return err
+}
+
+// Clean cleans the current replicas, in the reverse order
+func (s *Shards) Clean() {
+	for i := len(s.replicas) - 1; i >= 0; i-- {
+		s.replicas[i].player.Clean()
+	}
+}
+
+func shardName(i int) string {
+	return fmt.Sprintf("shard-%d", i)
+}
+
+// start plays the replica in its own goroutine
+func (s *Shards) start(ctx context.Context, r *replica) {
+	var rctx context.Context
+	rctx, r.cancel = context.WithCancel(ctx)
+	r.done = make(chan struct{})
+	go func() {
+		r.err = r.player.Play(rctx)
+		r.cancel()
+		close(r.done)
+		select {
+		case s.exited <- struct{}{}:
+		default:
+		}
+	}()
+}
+
+// stop cancels the given replicas, and waits for them to return, replicas that were never started are left alone
+func (s *Shards) stop(rs []*replica) {
+	for _, r := range rs {
+		if r.cancel != nil {
+			r.cancel()
+		}
+	}
+	for _, r := range rs {
+		if r.done != nil {
+			<-r.done
+		}
+		r.seen = true
+	}
+}
+
+// playing reports whether any replica is still playing
+func (s *Shards) playing() bool {
+	for _, r := range s.replicas {
+		if !r.seen {
+			return true
+		}
+	}
+	return false
+}
+
+// rebalance changes the number of replicas to n, stopping the old ones before starting the new ones
+func (s *Shards) rebalance(ctx context.Context, n int) error {
+	m := len(s.replicas)
+	kept := s.replicas
+	if n < m {
+		kept = s.replicas[:n]
+	}
+
+	rebalancing := true
+	for _, r := range kept {
+		if _, ok := as[Rebalancer](r.player); !ok || r.seen {
+			rebalancing = false
+			break
+		}
+	}
+
+	if !rebalancing {
+		// restart everyone that's still playing with their new assignment, the ones that have returned are done
+		old := s.replicas
+		returned := make([]bool, m)
+		var live []*replica
+		for i, r := range old {
+			returned[i] = r.seen
+			if !r.seen {
+				live = append(live, r)
+			}
+		}
+		s.stop(live)
+		for i := m - 1; i >= 0; i-- {
+			if !returned[i] || i >= n {
+				old[i].player.Clean()
+			}
+		}
+		s.replicas = nil
+		for i := 0; i < n; i++ {
+			if i < m && returned[i] {
+				s.replicas = append(s.replicas, old[i])
+				continue
+			}
+			p := s.factory(i, n)
+			if err := p.Setup(); err != nil {
+				for j := i + 1; j < n && j < m; j++ {
+					if returned[j] {
+						old[j].player.Clean() // they aren't on s.replicas yet, so they wouldn't be cleaned otherwise
+					}
+				}
+				return ErrSetup{Player: shardName(i), Err: err}
+			}
+			s.replicas = append(s.replicas, &replica{player: p})
+		}
+		for _, r := range s.replicas {
+			if r.done == nil {
+				s.start(ctx, r)
+			}
+		}
+		return nil
+	}
+
+	gone := s.replicas[len(kept):]
+	s.stop(gone)
+	for i := len(gone) - 1; i >= 0; i-- {
+		gone[i].player.Clean()
+	}
+	s.replicas = kept
+
+	for i, r := range kept {
+		rb, _ := as[Rebalancer](r.player)
+		if err := rb.Revoke(i, m); err != nil {
+			return ErrSetup{Player: shardName(i), Err: err}
+		}
+	}
+	for i, r := range kept {
+		rb, _ := as[Rebalancer](r.player)
+		if err := rb.Assign(i, n); err != nil {
+			return ErrSetup{Player: shardName(i), Err: err}
+		}
+	}
+
+	for i := m; i < n; i++ {
+		p := s.factory(i, n)
+		if err := p.Setup(); err != nil {
+			return ErrSetup{Player: shardName(i), Err: err}
+		}
+		r := &replica{player: p}
+		s.replicas = append(s.replicas, r)
+		s.start(ctx, r)
+	}
+	return nil
+}
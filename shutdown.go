@@ -0,0 +1,125 @@
+package orchestra
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// StopOrder sets the order in which the player is stopped, when the stage is cancelled, independently of the order it was setup in.
+// The players are stopped in waves, the players with the lowest stop order are cancelled first, and the next wave is cancelled
+// only after every player of the previous wave has returned from its Play.
+// The default is 0, so, for eg, `orchestra.StopOrder(-1)` on an ingress server stops it before everyone else,
+// and `orchestra.StopOrder(1)` on an outbox flusher stops it after everyone else.
+// Only the Plays that have started are stopped in waves, the players that are still waiting to Play (for eg, `orchestra.Latched`, or `orchestra.Exclusive` ones) give up as soon as the stage is cancelled,
+// and no player is restarted after it. The stop order is ignored by `orchestra.Sequential` stages, as they only have one player playing at a time.
+func StopOrder(n int) Option {
+	return func(e *entry) {
+		e.stopOrder = n
+	}
+}
+
+// ShutdownBudget bounds how long stopping the players in waves (see `orchestra.StopOrder`) can take, once the stage is cancelled.
+// The budget is apportioned across the waves by their weights (see `orchestra.WaveWeight`), and whatever a wave doesn't use rolls over to the ones after it,
+// so an early wave can't consume the entire budget, and starve the later ones.
+// Once a wave has used up its share, the next wave is cancelled, even if some players of the wave haven't returned yet.
+// Whatever is left of the budget once Play returns is what the players have to clean up, see `orchestra.ContextCleaner`.
+// Note: the budget doesn't make Play return any sooner, it still waits for every player to return
+func ShutdownBudget(d time.Duration) StageOption {
+	return func(s *Stage) {
+		s.budget = d
+	}
+}
+
+// WaveWeight sets the weight of the wave of players with the given stop order, when apportioning the `orchestra.ShutdownBudget`, it's 1 by default.
+// For eg, with `orchestra.WaveWeight(1, 3)`, the wave with stop order 1 gets thrice the time of any other wave.
+func WaveWeight(order, weight int) StageOption {
+	return func(s *Stage) {
+		if s.waveWeights == nil {
+			s.waveWeights = make(map[int]int)
+		}
+		s.waveWeights[order] = weight
+	}
+}
+
+// wave is a group of players that are stopped together
+type wave struct {
+	order   int
+	weight  int // see `orchestra.WaveWeight`
+	ctx     context.Context
+	cancel  context.CancelFunc
+	players []*entry
+}
+
+// waves groups the players by their stop order, in the order they're stopped, weighing them by the given weights
+func waves(players []*entry, weights map[int]int) []*wave {
+	byOrder := make(map[int]*wave)
+	var ws []*wave
+	for _, it := range players {
+		w, ok := byOrder[it.stopOrder]
+		if !ok {
+			w = &wave{order: it.stopOrder, weight: 1}
+			if n, ok := weights[it.stopOrder]; ok {
+				w.weight = n
+			}
+			byOrder[it.stopOrder] = w
+			ws = append(ws, w)
+		}
+		w.players = append(w.players, it)
+	}
+	sort.Slice(ws, func(i, j int) bool {
+		return ws[i].order < ws[j].order
+	})
+	return ws
+}
+
+// stopInWaves waits for ctx to be done, and then cancels the waves one by one, waiting for every player of a wave to return (i.e. for its done channel to be closed) before cancelling the next.
+// If budget is non-zero, a wave is waited on only for its share of whatever is left of the budget, see `orchestra.ShutdownBudget`.
+// It returns once all the waves have been cancelled, or once finished is closed.
+func stopInWaves(ctx context.Context, ws []*wave, done map[*entry]chan struct{}, finished <-chan struct{}, budget time.Duration) {
+	defer func() {
+		for _, w := range ws {
+			w.cancel()
+		}
+	}()
+	select {
+	case <-ctx.Done():
+	case <-finished:
+		return
+	}
+	deadline := time.Now().Add(budget)
+	for i, w := range ws {
+		w.cancel()
+		var expired <-chan time.Time // never fires if there's no budget
+		if budget > 0 {
+			t := time.NewTimer(share(time.Until(deadline), ws[i:]))
+			defer t.Stop()
+			expired = t.C
+		}
+	wait:
+		for _, it := range w.players {
+			select {
+			case <-done[it]:
+			case <-expired:
+				break wait // out of its share, it's the next wave's turn
+			case <-finished:
+				return
+			}
+		}
+	}
+}
+
+// share returns the share of the first of the given waves in what's left of the budget
+func share(left time.Duration, ws []*wave) time.Duration {
+	if left <= 0 {
+		return 0
+	}
+	total := 0
+	for _, w := range ws {
+		total += w.weight
+	}
+	if total <= 0 {
+		return left / time.Duration(len(ws))
+	}
+	return time.Duration(int64(left) * int64(ws[0].weight) / int64(total))
+}
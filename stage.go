@@ -1,146 +1,1025 @@
-// Package orchestra is a module that provides a minimal structure for orchestrating worker goroutines.
-// It defines a life cycle for the workers.
-package orchestra
-
-import (
-	"context"
-	"fmt"
-	"sync"
-)
-
-// ErrSetup is the error returned by (*Stage).Setup()
-type ErrSetup struct {
-	Player string // name of the player
-	Err    error  // the error returned by setup method of the player
-}
-
-func (e ErrSetup) Error() string {
-	return fmt.Sprintf("ErrSetup: %s: %s", e.Player, e.Err)
-}
-
-// ErrPlay is the error returned by (*Stage).Play()
-type ErrPlay struct {
-	Players map[string]error
-}
-
-func (e *ErrPlay) Error() string {
-	k := "ErrPlay:"
-	for name, err := range e.Players {
-		k += fmt.Sprintf(" |%s: %s|", name, err)
-	}
-	return k
-}
-
-// Stage is the abstraction that allows services to be added and played together, and get cancelled.
-// It facilitates graceful shutdown
-//
-// Note: Stage also implements `orchestra.Player`, so stages can nested
-type Stage struct {
-	players   map[string]Player
-	beenSetup bool
-}
-
-// NewStage creates a new empty stage
-func NewStage() *Stage {
-	return &Stage{
-		players: make(map[string]Player),
-	}
-}
-
-// Add adds a player to the stage
-func (s *Stage) Add(name string, p Player) {
-	s.players[name] = p
-}
-
-// Setup sets up all the players in this stage.
-// If any player returns error while setting up, Setup returns immediately.
-// The stage is setup as a whole, "if any player fails to setup: The stage fails to setup".
-//
-// if err is non-nil, it is of type `ErrSetup`
-// also, if err is non-nil, all the players that were successfully setup, before the faulty one, will be cleaned
-func (s *Stage) Setup() error {
-	// (*Stage).beenSetup is set iff all players are setup with nil errors.
-	// because, "if any player fails to setup: The stage fails to setup"
-	var err error
-	var good []Player
-	var faulty string
-	for name, it := range s.players {
-		err = it.Setup()
-		if err != nil {
-			faulty = name
-			break
-		}
-		good = append(good, it)
-	}
-	if err != nil {
-		for _, it := range good {
-			it.Clean()
-		}
-		return ErrSetup{
-			Player: faulty,
-			Err:    err,
-		}
-	}
-	s.beenSetup = true
-	return nil
-}
-
-// Clean calls Clean on every player in this stage
-func (s *Stage) Clean() {
-	wg := &sync.WaitGroup{}
-	wg.Add(len(s.players))
-	for _, it := range s.players {
-		go func(player Player) {
-			defer wg.Done()
-			player.Clean()
-		}(it)
-	}
-	wg.Wait()
-}
-
-// Play starts a goroutine for every player in this stage, and calls each player's Play from within.
-// It blocks till all the player returns, all the errors returned by the players are accumlated.
-// Also, (*Stage).Play panics if the stage hasn't been setup successfully, i.e. with nil error
-//
-// A non-nil error is returned iff at least one player returned a non-nil error
-func (s *Stage) Play(ctx context.Context) error {
-	if !s.beenSetup {
-		panic("(*Stage).Play: The stage hasn't been successfully setup")
-	}
-	wg := &sync.WaitGroup{}
-	wg.Add(len(s.players))
-	echan := make(chan struct {
-		Name string
-		Err  error
-	}, len(s.players))
-
-	for name, it := range s.players {
-		go func(n string, player Player) {
-			defer wg.Done()
-			e := player.Play(ctx)
-			if e != nil {
-				echan <- struct {
-					Name string
-					Err  error
-				}{Name: n, Err: e} // this send will never block
-			}
-		}(name, it)
-	}
-
-	wg.Wait()    // wait till all the players are done with their shit
-	close(echan) // since all the players are done, we can safely close this channel
-
-	var err *ErrPlay = nil
-	for e := range echan {
-		if err == nil {
-			err = &ErrPlay{
-				Players: make(map[string]error),
-			}
-		}
-		err.Players[e.Name] = e.Err
-	}
-	if err == nil {
-		return nil
-	}
-	return err
-}
+// Package orchestra is a module that provides a minimal structure for orchestrating worker goroutines.
+// It defines a life cycle for the workers.
+package orchestra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"runtime/trace"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrSetup is the error returned by (*Stage).Setup()
+type ErrSetup struct {
+	Player   string        // name of the player
+	Err      error         // the error returned by setup method of the player
+	Duration time.Duration // how long the failed setup took
+}
+
+func (e ErrSetup) Error() string {
+	return fmt.Sprintf("ErrSetup: %s: %s", e.Player, e.Err)
+}
+
+// Unwrap returns the error returned by the setup of the player, so the errors wrapped in it, like `ErrDependency`, can be checked with errors.As
+func (e ErrSetup) Unwrap() error {
+	return e.Err
+}
+
+// ErrPlay is the error returned by (*Stage).Play()
+type ErrPlay struct {
+	Players   map[string]error
+	Durations map[string]time.Duration // how long each of the failed players played, if known
+}
+
+// Error lists the players sorted by their names, so the message is the same for the same errors
+func (e *ErrPlay) Error() string {
+	k := "ErrPlay:"
+	for _, name := range e.names() {
+		k += fmt.Sprintf(" |%s: %s|", name, e.Players[name])
+	}
+	return k
+}
+
+// names returns the names of the failed players, sorted
+func (e *ErrPlay) names() []string {
+	names := make([]string, 0, len(e.Players))
+	for name := range e.Players {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ErrDependency is the error (wrapped in `ErrSetup`) when the dependencies of a player can't be satisfied
+type ErrDependency struct {
+	Player string // the player that has the dependency
+	On     string // the player it depends on
+	Reason string
+}
+
+func (e ErrDependency) Error() string {
+	return fmt.Sprintf("ErrDependency: %s -> %s: %s", e.Player, e.On, e.Reason)
+}
+
+// ErrNoPlayer is the error returned when a player is referred to by a name that isn't on the stage
+type ErrNoPlayer struct {
+	Player string
+}
+
+func (e ErrNoPlayer) Error() string {
+	return fmt.Sprintf("ErrNoPlayer: %s", e.Player)
+}
+
+// ErrResource is the error (wrapped in `ErrSetup`) when a player weighs against a resource that can never satisfy it
+type ErrResource struct {
+	Player   string
+	Resource string
+	Reason   string
+}
+
+func (e ErrResource) Error() string {
+	return fmt.Sprintf("ErrResource: %s: %s: %s", e.Player, e.Resource, e.Reason)
+}
+
+// ErrTimeout is the error returned when playing is cut short because it ran out of time
+type ErrTimeout struct {
+	Timeout time.Duration // the time that ran out
+	Err     error         // the error returned by the play that was cut short, if any
+}
+
+func (e ErrTimeout) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("ErrTimeout: %s", e.Timeout)
+	}
+	return fmt.Sprintf("ErrTimeout: %s: %s", e.Timeout, e.Err)
+}
+
+// Unwrap returns the error returned by the play that was cut short
+func (e ErrTimeout) Unwrap() error {
+	return e.Err
+}
+
+// Is makes `errors.Is(err, context.DeadlineExceeded)` hold for timeouts
+func (e ErrTimeout) Is(target error) bool {
+	return target == context.DeadlineExceeded
+}
+
+// Stage is the abstraction that allows services to be added and played together, and get cancelled.
+// It facilitates graceful shutdown
+//
+// Note: Stage also implements `orchestra.Player`, so stages can nested
+//
+// Setup, Play and Clean are each traced as a runtime/trace task, with a region for every player named "setup:<name>", "play:<name>", and "clean:<name>",
+// so it's easy to tell the players apart in `go tool trace`
+//
+// The introspection methods, like Status, Dump, StartupReport, Contained, and Plan, are safe to call at any time, from any goroutine, even while the stage is playing.
+// They never wait on a player, as the stage only holds its lock for bookkeeping, and never while calling into a player.
+// Players added while the stage is playing are only played once it's setup again.
+type Stage struct {
+	mu        sync.Mutex // guards the players, and their status
+	recent    []record   // the most recent errors returned by the players
+	players   map[string]*entry
+	order     []string // names of the players, in the order they were added
+	setup     []*entry // the players in the order they were setup
+	beenSetup bool
+
+	exclusive map[string]*semaphore // the mutual exclusion groups, see `orchestra.Exclusive`
+	resources map[string]*semaphore // the resource pools, see `orchestra.Resource`
+	groups    map[string]*group     // see `orchestra.Group`
+	recycling *semaphore            // makes sure that players are recycled one at a time, see `orchestra.MaxLifetime`
+	pressure  pressure              // see (*Stage).Preempt
+
+	sequential  bool            // see `orchestra.Sequential`
+	whenEmpty   Mode            // see `orchestra.WhenEmpty`
+	onError     ErrorHandler    // see `orchestra.OnError`
+	journal     *journal        // see `orchestra.Journal`
+	deadLetter  DeadLetter      // see `orchestra.DeadLetters`
+	reporter    Reporter        // see `orchestra.Report`
+	recover     bool            // see `orchestra.Recover`
+	alarms      []*alarm        // see `orchestra.AlertOn`
+	meter       Meter           // see `orchestra.Metrics`
+	subscribers []*Subscription // see (*Stage).Subscribe
+	inspect     io.Writer       // see `orchestra.InspectOnSignal`
+	store       Store           // see `orchestra.Persist`
+
+	history     History       // see `orchestra.CrashHistory`
+	crashWindow time.Duration // see `orchestra.CrashHistory`
+	exitCode    int           // see `orchestra.ExitCode`
+	fatally     sync.Once     // makes sure that only the first call to `orchestra.Fatal` takes effect
+	dying       bool          // set once a player calls `orchestra.Fatal`, so no player is played, or restarted after it
+
+	budget      time.Duration // see `orchestra.ShutdownBudget`
+	shutdown    time.Time     // when the shutdown budget runs out, zero if the stage isn't shutting down
+	waveWeights map[int]int   // see `orchestra.WaveWeight`
+
+	contained map[string]error // the errors of the non-critical, and optional players, see `orchestra.NonCritical`
+	logger    *slog.Logger     // see `orchestra.Logger`, nil if the stage doesn't log
+
+	ready   chan struct{}  // see (*Stage).Ready, created lazily
+	startup *StartupReport // the report of the last Setup, see (*Stage).StartupReport
+
+	path  string // the names of the stages this stage is nested in, and its own name, separated by "/"
+	owner *owned // the stage this stage is nested in, and its entry there, nil if it isn't nested
+}
+
+// NewStage creates a new empty stage
+func NewStage(opts ...StageOption) *Stage {
+	s := &Stage{
+		players:   make(map[string]*entry),
+		recycling: newSemaphore(1),
+		exitCode:  1,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Add adds a player to the stage, adding a player with an existing name replaces the old one.
+// Nested stages added to a stage report their failures to the reporter of the parent (see `orchestra.Report`), and log to the logger of the parent (see `orchestra.Logger`),
+// unless they have their own, and recover panics if the parent does (see `orchestra.Recover`).
+func (s *Stage) Add(name string, p Player, opts ...Option) {
+	e := &entry{
+		name:   name,
+		player: p,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if nested, ok := p.(*Stage); ok {
+		e.nested = nested
+		nested.owner = &owned{stage: s, entry: e}
+		nested.path = name
+		if s.path != "" {
+			nested.path = s.path + "/" + name
+		}
+		if nested.reporter == nil {
+			nested.reporter = s.reporter
+		}
+		if nested.logger == nil {
+			nested.logger = s.logger
+		}
+		if nested.history == nil {
+			nested.history, nested.crashWindow = s.history, s.crashWindow
+		}
+		nested.recover = nested.recover || s.recover
+	}
+	if _, ok := s.players[name]; !ok {
+		s.order = append(s.order, name)
+	}
+	s.players[name] = e
+}
+
+// Wrap replaces every player on the stage with the player returned by fn, which is usually a wrapper around the original player.
+// It's meant for instrumenting a stage, for eg, with `orchestra.Chaos`.
+// It must be called before Setup, and the players added after it aren't wrapped.
+// Wrappers should implement `orchestra.Wrapper`, so the optional interfaces of the original player are still honored.
+func (s *Stage) Wrap(fn func(name string, p Player) Player) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, name := range s.order {
+		e := s.players[name]
+		e.player = fn(name, e.player)
+	}
+}
+
+// Wrapper is implemented by players that wrap another player, for eg, the ones returned by `orchestra.Chaos`, and `orchestra.Checked`.
+// The stage looks through wrappers for the optional interfaces of the players, like `orchestra.Warmer`, `orchestra.Resumer`, and `orchestra.HealthChecker`,
+// so wrapping a player doesn't change how it's handled. The exception is `orchestra.ContextCleaner`, as Clean is part of the life cycle,
+// wrappers have to implement it themselves, and forward it to the player they wrap.
+type Wrapper interface {
+	Unwrap() Player
+}
+
+// as returns the first player in the chain of wrappers around p (p included) that implements T
+func as[T any](p Player) (T, bool) {
+	for {
+		if t, ok := p.(T); ok {
+			return t, true
+		}
+		w, ok := p.(Wrapper)
+		if !ok {
+			var zero T
+			return zero, false
+		}
+		p = w.Unwrap()
+	}
+}
+
+// Release lets a player added with `orchestra.Latched` Play.
+// It can be called before or during (*Stage).Play, releasing a player more than once, or one that isn't latched does nothing.
+func (s *Stage) Release(name string) error {
+	s.mu.Lock()
+	e, ok := s.players[name]
+	s.mu.Unlock()
+	if !ok {
+		return ErrNoPlayer{Player: name}
+	}
+	if e.latch != nil {
+		e.release.Do(func() { close(e.latch) })
+	}
+	return nil
+}
+
+// sorted returns the players such that every player comes after the players it depends on,
+// otherwise the players keep the order they were added in. The caller must hold (*Stage).mu
+func (s *Stage) sorted() ([]*entry, error) {
+	for _, name := range s.order {
+		for _, dep := range s.players[name].after {
+			if _, ok := s.players[dep]; !ok {
+				return nil, ErrSetup{
+					Player: name,
+					Err:    ErrDependency{Player: name, On: dep, Reason: "no such player"},
+				}
+			}
+		}
+	}
+
+	placed := make(map[string]bool, len(s.order))
+	sorted := make([]*entry, 0, len(s.order))
+	for len(sorted) < len(s.order) {
+		progress := false
+	next:
+		for _, name := range s.order {
+			if placed[name] {
+				continue
+			}
+			e := s.players[name]
+			for _, dep := range e.after {
+				if !placed[dep] {
+					continue next
+				}
+			}
+			placed[name] = true
+			sorted = append(sorted, e)
+			progress = true
+		}
+		if !progress {
+			break
+		}
+	}
+	if len(sorted) < len(s.order) {
+		// everyone left is either in a cycle, or depends on one
+		for _, name := range s.order {
+			if !placed[name] {
+				e := s.players[name]
+				return nil, ErrSetup{
+					Player: name,
+					Err:    ErrDependency{Player: name, On: e.after[0], Reason: "dependency cycle"},
+				}
+			}
+		}
+	}
+	return sorted, nil
+}
+
+// Setup sets up all the players in this stage.
+// The players are setup one by one, in the order they were added, except that a player is always setup after the players it depends on.
+// If any player returns error while setting up, Setup returns immediately.
+// The stage is setup as a whole, "if any player fails to setup: The stage fails to setup".
+//
+// if err is non-nil, it is of type `ErrSetup`, the players added with `orchestra.NonCritical` never cause it
+// also, if err is non-nil, all the players that were successfully setup, before the faulty one, will be cleaned
+func (s *Stage) Setup() error {
+	// (*Stage).beenSetup is set iff all players are setup with nil errors.
+	// because, "if any player fails to setup: The stage fails to setup"
+	s.mu.Lock()
+	sorted, err := s.sorted()
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	ctx, task := trace.NewTask(context.Background(), "orchestra.Setup")
+	defer task.End()
+	s.mu.Lock()
+	s.shutdown = time.Time{} // a new run, with a new budget
+	for _, g := range s.groups {
+		g.stopped = false // the players of a stopped group are setup again, so they get to play again
+	}
+	s.mu.Unlock()
+	s.restore()
+	for _, it := range sorted {
+		for _, name := range it.resources() {
+			r, ok := s.resources[name]
+			reason := ""
+			switch {
+			case !ok:
+				reason = "no such resource"
+			case it.weights[name] > r.size:
+				reason = fmt.Sprintf("weight %d is more than the capacity %d", it.weights[name], r.size)
+			}
+			if reason != "" {
+				return ErrSetup{
+					Player: it.name,
+					Err:    ErrResource{Player: it.name, Resource: name, Reason: reason},
+				}
+			}
+		}
+		if err := s.quotaOf(it); err != nil {
+			return ErrSetup{Player: it.name, Err: err}
+		}
+	}
+	var good, attempted []*entry
+	var faulty string
+	var took time.Duration
+	began := time.Now()
+	for _, it := range sorted {
+		start := time.Now()
+		attempted = append(attempted, it)
+		s.setBroken(it, false)
+		err = it.missing()
+		if err == nil {
+			err = s.brokenDependency(it)
+		}
+		if err == nil {
+			err = s.quarantined(it)
+		}
+		if err == nil {
+			trace.WithRegion(ctx, "setup:"+it.name, func() {
+				err = s.setupPlayer(it)
+			})
+		}
+		took = time.Since(start)
+		s.mu.Lock()
+		it.status.setupTook = took
+		s.mu.Unlock()
+		if err != nil {
+			s.transition(it, StateFailed, PhaseSetup, err)
+			if it.nonCritical {
+				// contained, it can only be played if it's restarted
+				s.setBroken(it, true)
+				s.contain(it, err)
+				err = nil
+				continue
+			}
+			faulty = it.name
+			break
+		}
+		s.transition(it, StateSetup, PhaseSetup, nil)
+		good = append(good, it)
+	}
+	if err != nil {
+		// clean up in the reverse order, so no one is left with a dependency that has been cleaned
+		for i := len(good) - 1; i >= 0; i-- {
+			trace.WithRegion(ctx, "clean:"+good[i].name, func() { cleanPlayer(ctx, good[i].player) })
+			good[i].removeScratch()
+			s.transition(good[i], StateCleaned, PhaseClean, nil)
+		}
+		err = ErrSetup{
+			Player:   faulty,
+			Err:      err,
+			Duration: took,
+		}
+		s.reportStartup(began, attempted, err)
+		return err
+	}
+	s.mu.Lock()
+	s.exclusive = make(map[string]*semaphore)
+	for _, it := range sorted {
+		if it.exclusive != "" && s.exclusive[it.exclusive] == nil {
+			s.exclusive[it.exclusive] = newSemaphore(1)
+		}
+	}
+	s.mu.Unlock()
+	s.mu.Lock()
+	s.setup = sorted
+	s.beenSetup = true
+	s.mu.Unlock()
+	s.reportStartup(began, attempted, nil)
+	s.markReady()
+	return nil
+}
+
+// setBroken marks the player as broken, or not. It's written under the lock, as it's read by the other players' goroutines, for eg, by (*Stage).Health,
+// the player's own goroutine can read it without the lock
+func (s *Stage) setBroken(it *entry, broken bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	it.broken = broken
+}
+
+// brokenDependency reports a dependency of the player that failed to setup, which can only happen if the dependency is non-critical
+func (s *Stage) brokenDependency(e *entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, dep := range e.after {
+		if s.players[dep].broken {
+			return ErrDependency{Player: e.name, On: dep, Reason: "failed to setup"}
+		}
+	}
+	return nil
+}
+
+// contain records the error of a non-critical player
+func (s *Stage) contain(e *entry, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.contained == nil {
+		s.contained = make(map[string]error)
+	}
+	s.contained[e.name] = err
+}
+
+// Contained returns the last error of every non-critical, or optional player that failed (see `orchestra.NonCritical` and `orchestra.Optional`), by the name of the player
+func (s *Stage) Contained() map[string]error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	contained := make(map[string]error, len(s.contained))
+	for name, err := range s.contained {
+		contained[name] = err
+	}
+	return contained
+}
+
+// unset forgets the outputs produced by the player, so the ones it doesn't set again during its next Setup are reported missing,
+// instead of handing the value of its previous instance over to the consumers
+func (s *Stage) unset(producer *entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range s.players {
+		for _, d := range e.uses {
+			if d.producer() == producer.name {
+				d.unset()
+			}
+		}
+	}
+}
+
+// missing reports an output used by the player that hasn't been set by its producer
+func (e *entry) missing() error {
+	for _, d := range e.uses {
+		if !d.produced() {
+			return ErrDependency{Player: e.name, On: d.producer(), Reason: "output wasn't set during setup"}
+		}
+	}
+	return nil
+}
+
+// Clean calls Clean on every player in this stage.
+// The players are cleaned concurrently, except that a player is cleaned only after all the players depending on it have been cleaned.
+// The players that implement `orchestra.ContextCleaner` are cleaned within what's left of the shutdown budget, see (*Stage).CleanContext
+func (s *Stage) Clean() {
+	s.CleanContext(context.Background())
+}
+
+func (s *Stage) clean(ctx context.Context) {
+	ctx, task := trace.NewTask(ctx, "orchestra.Clean")
+	defer task.End()
+	s.mu.Lock()
+	players := s.setup
+	ordered := true
+	if players == nil {
+		var err error
+		players, err = s.sorted()
+		if err != nil {
+			// the dependencies are broken, so there's no order to respect
+			players, ordered = nil, false
+			for _, name := range s.order {
+				players = append(players, s.players[name])
+			}
+		}
+	}
+	s.mu.Unlock()
+	done := make(map[string]chan struct{}, len(players))
+	dependents := make(map[string][]chan struct{}, len(players))
+	for _, it := range players {
+		done[it.name] = make(chan struct{})
+	}
+	for _, it := range players {
+		if !ordered {
+			break
+		}
+		for _, dep := range it.after {
+			dependents[dep] = append(dependents[dep], done[it.name])
+		}
+	}
+
+	wg := &sync.WaitGroup{}
+	wg.Add(len(players))
+	for _, it := range players {
+		go func(e *entry) {
+			defer wg.Done()
+			defer close(done[e.name])
+			for _, d := range dependents[e.name] {
+				<-d
+			}
+			if e.broken {
+				return // it failed to setup again after a restart
+			}
+			trace.WithRegion(ctx, "clean:"+e.name, func() { cleanPlayer(ctx, e.player) })
+			e.removeScratch()
+			s.transition(e, StateCleaned, PhaseClean, nil)
+		}(it)
+	}
+	wg.Wait()
+}
+
+// Play starts a goroutine for every player in this stage, and calls each player's Play from within.
+// It blocks till all the player returns, all the errors returned by the players are accumlated.
+// If the stage is `orchestra.Sequential`, the players are played one at a time instead.
+// The init players (see `orchestra.Init`) are played to completion before any other player.
+// If the stage has no players, Play returns right away, or blocks until ctx is done, as per `orchestra.WhenEmpty`.
+// The context given to each player is derived from ctx, and is cancelled as soon as the player's Play returns.
+// If the players have different stop orders (see `orchestra.StopOrder`), the context given to them carries the values of ctx,
+// but is cancelled wave by wave after ctx is done.
+// Also, (*Stage).Play panics if the stage hasn't been setup successfully, i.e. with nil error
+//
+// A non-nil error is returned iff at least one player returned a non-nil error
+func (s *Stage) Play(ctx context.Context) error {
+	s.mu.Lock()
+	beenSetup, setup := s.beenSetup, s.setup
+	s.mu.Unlock()
+	if !beenSetup {
+		panic("(*Stage).Play: The stage hasn't been successfully setup")
+	}
+	ctx, task := trace.NewTask(ctx, "orchestra.Play")
+	defer task.End()
+	inspecting, stopInspecting := context.WithCancel(ctx)
+	defer stopInspecting()
+	s.inspectOnSignal(inspecting)
+	defer context.AfterFunc(ctx, s.shuttingDown)()
+
+	if len(setup) == 0 && s.whenEmpty == ModeService {
+		<-ctx.Done()
+		return nil
+	}
+
+	players := setup
+	if once, rest := inits(setup); len(once) > 0 {
+		if err := s.playInits(ctx, once); err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return nil // cancelled before the rest of the players could Play
+		}
+		players = rest
+	}
+
+	wg := &sync.WaitGroup{}
+	wg.Add(len(players))
+	echan := make(chan struct {
+		Name string
+		Err  error
+	}, len(players))
+
+	// the players get their own contexts, detached from ctx, if they have to be stopped in waves
+	pctxs := make(map[*entry]context.Context, len(players))
+	done := make(map[*entry]chan struct{}, len(players))
+	finished := make(chan struct{})
+	defer close(finished)
+	// a sequential stage only has one player playing at a time, so there's no one to stop in waves, and waiting on a wave whose players haven't started yet would deadlock
+	if ws := waves(players, s.waveWeights); len(ws) > 1 && !s.sequential {
+		base := context.WithoutCancel(ctx)
+		for _, w := range ws {
+			w.ctx, w.cancel = context.WithCancel(base)
+			for _, it := range w.players {
+				pctxs[it] = w.ctx
+				done[it] = make(chan struct{})
+			}
+		}
+		go stopInWaves(ctx, ws, done, finished, s.budget)
+	}
+
+	for _, it := range players {
+		run := func(it *entry) {
+			defer wg.Done()
+			pctx, ok := pctxs[it]
+			if !ok {
+				pctx = ctx
+			} else {
+				defer close(done[it])
+			}
+			e := s.play(ctx, pctx, it)
+			if e != nil {
+				echan <- struct {
+					Name string
+					Err  error
+				}{Name: it.name, Err: e} // this send will never block
+			}
+		}
+		if s.sequential {
+			run(it)
+			continue
+		}
+		go run(it)
+	}
+
+	wg.Wait()    // wait till all the players are done with their shit
+	close(echan) // since all the players are done, we can safely close this channel
+
+	var err *ErrPlay = nil
+	for e := range echan {
+		if err == nil {
+			err = &ErrPlay{
+				Players:   make(map[string]error),
+				Durations: make(map[string]time.Duration),
+			}
+		}
+		err.Players[e.Name] = e.Err
+		s.mu.Lock()
+		st := s.players[e.Name].status
+		s.mu.Unlock()
+		if !st.started.IsZero() && !st.stopped.IsZero() {
+			err.Durations[e.Name] = st.stopped.Sub(st.started)
+		}
+	}
+	if err == nil {
+		return nil
+	}
+	return err
+}
+
+// errSkipped is returned by (*Stage).playOnce when the player's Play wasn't called, because the stage was cancelled while it waited
+var errSkipped = errors.New("skipped")
+
+// play runs a single player through its Play, honoring the options it was added with.
+// ctx is the context of the stage, the player waits for its turn, and is restarted only until it's done, while pctx is the one its Play is derived from.
+// They're the same, unless the players are stopped in waves (see `orchestra.StopOrder`), in which case a player that hasn't started playing by the time the stage is cancelled never does
+func (s *Stage) play(ctx, pctx context.Context, it *entry) error {
+	if it.latch != nil || it.gate != nil {
+		s.transition(it, StateWaiting, PhasePlay, nil)
+	}
+	if !it.wait(ctx) {
+		return nil // cancelled before it was released
+	}
+
+	restarts := s.crashes(it).Count // the restarts used up before the process restarted, see `orchestra.CrashHistory`
+	err := errRestarted             // i.e. it's ready to be played
+	if it.broken {
+		// a non-critical player that failed to setup, it has already been reported, and can only play if it's restarted
+		if !it.restart.allows(restarts) {
+			return nil
+		}
+		s.mu.Lock()
+		err = it.status.err
+		s.mu.Unlock()
+	}
+	for {
+		if err == errRestarted {
+			if it.window != nil {
+				err = s.windowed(ctx, pctx, it)
+			} else {
+				err = s.playOnce(ctx, pctx, it)
+			}
+		}
+		if err == errExpired {
+			err = s.recycle(it)
+		}
+		if err == errPaused {
+			err = errRestarted // it's played again once its group is resumed
+			continue
+		}
+		for err != nil && err != errSkipped && err != errRestarted && ctx.Err() == nil && it.restart.allows(restarts) {
+			restarts++
+			err = s.restart(ctx, it, err)
+		}
+		if err != errRestarted {
+			break
+		}
+	}
+	if err == errSkipped {
+		return nil
+	}
+	if err != nil {
+		phase := PhasePlay
+		if it.broken {
+			phase = PhaseSetup
+		}
+		s.transition(it, StateFailed, phase, err)
+		if phase == PhasePlay {
+			s.crashed(it)
+		}
+		if it.optional {
+			s.contain(it, err)
+			s.log(slog.LevelWarn, "optional player failed", "player", it.name, "phase", phase, "error", err)
+			return nil
+		}
+		return err
+	}
+	s.transition(it, StateDone, PhasePlay, nil)
+	s.forget(it)
+	return nil
+}
+
+// errRestarted is returned by (*Stage).restart once the player is ready to be played again
+var errRestarted = errors.New("restarted")
+
+// restart absorbs the error that made the player fail, and sets it up again after its backoff.
+// it returns errRestarted if the player is ready to be played again, otherwise the error that prevented it
+func (s *Stage) restart(ctx context.Context, it *entry, cause error) error {
+	phase := PhasePlay
+	if it.broken {
+		phase = PhaseSetup
+	}
+	s.transition(it, StateRestarting, phase, cause)
+	s.mu.Lock()
+	it.status.restarts++
+	s.mu.Unlock()
+	if s.meter != nil {
+		s.meter.Inc(MetricRestarts, s.labels(it))
+	}
+	if phase == PhasePlay {
+		s.crashed(it)
+	}
+	if s.deadLetter != nil {
+		s.deadLetter.Absorb(Event{Time: time.Now(), Player: it.name, Group: it.group, State: StateRestarting, Phase: phase, Err: cause})
+	}
+	if !sleep(ctx, it.restart.backoff) {
+		return errSkipped // the stage was cancelled while backing off
+	}
+	return s.reset(it)
+}
+
+// reset cleans the player (unless it's broken), and sets it up again, handing its state over (see `orchestra.Resumer`).
+// it returns errRestarted if the player is ready to be played again, otherwise the error that prevented it
+func (s *Stage) reset(it *entry) error {
+	s.mu.Lock()
+	dying := s.dying
+	s.mu.Unlock()
+	if dying {
+		return errSkipped // the process is going down, see `orchestra.Fatal`
+	}
+	it.export()
+	if !it.broken {
+		cleanPlayer(context.Background(), it.player)
+		it.removeScratch()
+		s.transition(it, StateCleaned, PhaseClean, nil)
+	}
+	it.resume()
+	err := it.missing()
+	if err == nil {
+		err = s.setupPlayer(it)
+	}
+	if err != nil {
+		s.setBroken(it, true) // it isn't setup, so it mustn't be cleaned
+		return err
+	}
+	s.setBroken(it, false)
+	s.transition(it, StateSetup, PhaseSetup, nil)
+	return errRestarted
+}
+
+// playOnce calls the player's Play once it's allowed to, i.e. once it has acquired its exclusion group, and resources.
+// It waits as long as ctx isn't done, and derives the context given to Play from base, see (*Stage).play
+func (s *Stage) playOnce(ctx, base context.Context, it *entry) error {
+	if it.group != "" {
+		leave, err := s.enter(ctx, it)
+		if err != nil {
+			return err
+		}
+		defer leave()
+	}
+	if it.exclusive != "" {
+		s.transition(it, StateWaiting, PhasePlay, nil)
+		group := s.exclusive[it.exclusive]
+		if group.acquire(ctx, 1) != nil {
+			return errSkipped // cancelled while it was queued
+		}
+		defer group.release(1)
+	}
+	if len(it.weights) > 0 {
+		s.transition(it, StateWaiting, PhasePlay, nil)
+		for _, name := range it.resources() {
+			r, n := s.resources[name], it.weights[name]
+			if r.acquire(ctx, n) != nil {
+				return errSkipped // cancelled while it was waiting for resources
+			}
+			defer r.release(n)
+		}
+	}
+
+	if ctx.Err() != nil {
+		return errSkipped // cancelled while it waited, but it may have acquired everything anyway
+	}
+	pctx, cancel := s.playerContext(base, it)
+	defer cancel()
+	switch context.Cause(pctx) {
+	case errPaused:
+		return errPaused // it's played once the group is resumed
+	case errStopped, errFatal:
+		return errSkipped
+	}
+	expire := s.expire(pctx, it)
+
+	s.transition(it, StatePlaying, PhasePlay, nil)
+	region := trace.StartRegion(pctx, "play:"+it.name)
+	err := s.playPlayer(pctx, it)
+	region.End()
+	if expire() && (ctx.Err() != nil || context.Cause(pctx) != errExpired) {
+		s.recycling.release(1) // it was stopped for some other reason, so it's not going to be recycled
+	}
+	if ctx.Err() == nil {
+		switch context.Cause(pctx) {
+		case errFatal:
+			return errSkipped // the process is going down, it mustn't be restarted
+		case errExpired:
+			return errExpired // (*Stage).recycle releases the recycling token
+		case errPaused:
+			return errPaused
+		case errStopped, errIdle:
+			return nil
+		}
+	}
+	if base.Err() == nil && pctx.Err() == context.DeadlineExceeded {
+		// it was the player's own deadline, not the stage's
+		err = ErrTimeout{Timeout: it.timeout, Err: err}
+	}
+	return err
+}
+
+// playerContext derives the context for a single Play of the player from the context of the stage.
+// The context is cancelled once the returned func is called, so nothing started by the Play is left running,
+// and it can be cancelled on its own, with a cause, by (*Stage).CancelPlayer, (*Stage).PauseGroup, and so on
+func (s *Stage) playerContext(ctx context.Context, it *entry) (context.Context, func()) {
+	if it.dir != "" {
+		ctx = context.WithValue(ctx, scratchKey{}, it.dir)
+	}
+	ctx = context.WithValue(ctx, playerKey{}, &owned{stage: s, entry: it})
+	var idle *idler
+	if it.idleAfter > 0 {
+		idle = &idler{}
+		ctx = context.WithValue(ctx, idleKey{}, idle)
+	}
+	pctx, cancel := context.WithCancelCause(ctx)
+	stop := func() {}
+	if it.timeout > 0 {
+		pctx, stop = context.WithTimeout(pctx, it.timeout)
+	}
+	if idle != nil {
+		idle.cancel = cancel
+		go idle.watch(pctx, it.idleAfter)
+	}
+	returned := make(chan struct{})
+	s.mu.Lock()
+	it.cancel, it.returned = cancel, returned
+	if s.dying {
+		cancel(errFatal)
+	}
+	if it.group != "" {
+		// the group may have been paused, or stopped since the player entered it, see (*Stage).enter
+		switch g := s.groupOf(it.group); {
+		case g.stopped:
+			cancel(errStopped)
+		case g.resumed != nil:
+			cancel(errPaused)
+		}
+	}
+	s.mu.Unlock()
+	return pctx, func() {
+		s.mu.Lock()
+		it.cancel, it.returned = nil, nil
+		s.mu.Unlock()
+		close(returned)
+		stop()
+		cancel(context.Canceled)
+	}
+}
+
+// playerKey is the key of the player in the context given to its Play, see (*Stage).playerContext
+type playerKey struct{}
+
+// owned is a player, along with the stage it's on
+type owned struct {
+	stage *Stage
+	entry *entry
+}
+
+// playerOf returns the player the context was given to, and its stage, it's false if the context wasn't given to a player by a stage
+func playerOf(ctx context.Context) (*owned, bool) {
+	p, ok := ctx.Value(playerKey{}).(*owned)
+	return p, ok
+}
+
+// CancelPlayer cancels the context given to the current Play of the named player with the given cause, leaving the rest of the stage alone.
+// The cause can be retrieved by the player using context.Cause, and the error returned by its Play is handled as usual, for eg, it's restarted if it was added with `orchestra.Restart`.
+// Cancelling a player that isn't playing does nothing.
+func (s *Stage) CancelPlayer(name string, cause error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.players[name]
+	if !ok {
+		return ErrNoPlayer{Player: name}
+	}
+	if e.cancel != nil {
+		e.cancel(cause)
+	}
+	return nil
+}
+
+// windowed plays the player once in every window of its schedule, until the stage is cancelled, or the player fails, see (*Stage).play for ctx, and base
+func (s *Stage) windowed(ctx, base context.Context, it *entry) error {
+	played := false
+	for {
+		now := time.Now()
+		start, end := it.window.Next(now)
+		if start.After(now) {
+			s.transition(it, StateWaiting, PhasePlay, nil)
+			if !sleep(ctx, start.Sub(now)) {
+				break
+			}
+		}
+
+		wctx, cancel := context.WithDeadline(ctx, end)
+		wbase, cancelBase := context.WithDeadline(base, end)
+		err := s.playOnce(wctx, wbase, it)
+		closed := ctx.Err() == nil && wctx.Err() != nil
+		cancelBase()
+		cancel()
+		if err != errSkipped {
+			played = true
+		}
+		if err != nil && err != errSkipped {
+			// the player is expected to complain about being cancelled when the window closes
+			if !closed || !(errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled)) {
+				return err
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		// if the player returned before the window closed, it shouldn't be played again in the same window
+		s.transition(it, StateWaiting, PhasePlay, nil)
+		if !sleep(ctx, time.Until(end)) {
+			break
+		}
+	}
+	if !played {
+		return errSkipped
+	}
+	return nil
+}
+
+// sleep waits for d, and reports whether ctx was still alive by the end of it
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// PlayWithTimeout is like (*Stage).Play, except that the stage is played for at most d.
+// It's meant for batch jobs and tests that must never run unbounded.
+//
+// If the stage had to be cancelled because d ran out, the error is of type `ErrTimeout`,
+// wrapping the error returned by (*Stage).Play (if any).
+// Cancelling ctx doesn't count as a timeout.
+func (s *Stage) PlayWithTimeout(ctx context.Context, d time.Duration) error {
+	tctx, cancel := context.WithTimeout(ctx, d)
+	defer cancel()
+	err := s.Play(tctx)
+	if ctx.Err() == nil && tctx.Err() == context.DeadlineExceeded {
+		return ErrTimeout{Timeout: d, Err: err}
+	}
+	return err
+}
@@ -0,0 +1,79 @@
+package orchestra
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+type healthy struct {
+	SimplePlayer
+}
+
+func (healthy) Health(ctx context.Context) error {
+	return nil
+}
+
+// TestConcurrentIntrospection introspects, and mutates a stage from many goroutines while its players restart, and recycle, it's meant to be run with -race
+func TestConcurrentIntrospection(t *testing.T) {
+	crashing := SimplePlayer(func(ctx context.Context) error {
+		time.Sleep(time.Millisecond)
+		return errors.New("crashed")
+	})
+	blocking := SimplePlayer(func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+
+	s := NewStage()
+	s.Add("crashing", crashing, Restart(-1, time.Millisecond), Group("workers"))
+	s.Add("recycled", blocking, MaxLifetime(2*time.Millisecond, time.Millisecond), Group("workers"))
+	s.Add("checked", healthy{blocking}, After("crashing"))
+	s.Add("nested", NewStage())
+	other := NewStage()
+	other.Add("crashing", crashing)
+
+	if err := s.Setup(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	played := make(chan error, 1)
+	go func() {
+		played <- s.Play(ctx)
+	}()
+
+	introspect := []func(){
+		func() { s.Status() },
+		func() { s.Dump(io.Discard) },
+		func() { s.Health(ctx) },
+		func() { s.Plan() },
+		func() { s.StartupReport() },
+		func() { s.Contained() },
+		func() { Diff(s, other) },
+		func() { s.PauseGroup("workers") },
+		func() { s.ResumeGroup("workers") },
+		func() { s.Add("late", blocking) },
+	}
+	wg := &sync.WaitGroup{}
+	for _, fn := range introspect {
+		wg.Add(1)
+		go func(fn func()) {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				fn()
+			}
+		}(fn)
+	}
+	wg.Wait()
+
+	// the last crash after the stage is cancelled isn't restarted, see `orchestra.Restart`, so it's the only error
+	var e *ErrPlay
+	if err := <-played; err != nil && (!errors.As(err, &e) || len(e.Players) != 1 || e.Players["crashing"] == nil) {
+		t.Fatal(err)
+	}
+	s.Clean()
+}
@@ -0,0 +1,103 @@
+package orchestra
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// StartupReport describes how the last (*Stage).Setup went, so slow boots can be attributed to specific players
+type StartupReport struct {
+	Started time.Time
+	Took    time.Duration   // how long the whole Setup took
+	Players []PlayerStartup // the players, in the order they were setup, up to the one that failed (if any)
+	Err     error           // the error returned by Setup, if any
+}
+
+// PlayerStartup is how the Setup of a single player went, see `orchestra.StartupReport`
+type PlayerStartup struct {
+	Player string
+	Order  int           // the position of the player in the order of setup, starting with 0
+	Setup  time.Duration // how long the Setup of the player took
+	Warmup time.Duration // how long the player took to warm up, see `orchestra.Warmer`
+	Err    error         // the error the player failed to setup with, if any
+}
+
+// Slowest returns the player that took the longest to setup and warm up, it's false if there are no players
+func (r StartupReport) Slowest() (PlayerStartup, bool) {
+	var slowest PlayerStartup
+	found := false
+	for _, p := range r.Players {
+		if !found || p.Setup+p.Warmup > slowest.Setup+slowest.Warmup {
+			slowest, found = p, true
+		}
+	}
+	return slowest, found
+}
+
+// String describes the report, one line per player
+func (r StartupReport) String() string {
+	b := &strings.Builder{}
+	r.write(b, "")
+	return b.String()
+}
+
+func (r StartupReport) write(b *strings.Builder, indent string) {
+	fmt.Fprintf(b, "%sstartup: took %s", indent, r.Took)
+	if r.Err != nil {
+		fmt.Fprintf(b, ", failed: %s", r.Err)
+	}
+	b.WriteString("\n")
+	for _, p := range r.Players {
+		fmt.Fprintf(b, "%s  %d. %s: setup %s", indent, p.Order, p.Player, p.Setup)
+		if p.Warmup > 0 {
+			fmt.Fprintf(b, ", warmup %s", p.Warmup)
+		}
+		if p.Err != nil {
+			fmt.Fprintf(b, ", failed: %s", p.Err)
+		}
+		b.WriteString("\n")
+	}
+}
+
+// StartupReport returns the report of the last (*Stage).Setup, it's false if the stage hasn't been setup yet.
+// It's also included in (*Stage).Dump, and a summary is logged when Setup returns (see `orchestra.Logger`)
+func (s *Stage) StartupReport() (StartupReport, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.startup == nil {
+		return StartupReport{}, false
+	}
+	return *s.startup, true
+}
+
+// reportStartup records the report of the Setup that started at start, and went through the given players
+func (s *Stage) reportStartup(start time.Time, players []*entry, err error) {
+	r := &StartupReport{Started: start, Took: time.Since(start), Err: err}
+	s.mu.Lock()
+	for i, it := range players {
+		p := PlayerStartup{
+			Player: it.name,
+			Order:  i,
+			Setup:  it.status.setupTook - it.status.warmupTook,
+			Warmup: it.status.warmupTook,
+		}
+		if it.status.state == StateFailed {
+			p.Err = it.status.err
+		}
+		r.Players = append(r.Players, p)
+	}
+	s.startup = r
+	s.mu.Unlock()
+
+	args := []any{"took", r.Took, "players", len(r.Players)}
+	if slowest, ok := r.Slowest(); ok {
+		args = append(args, "slowest", slowest.Player, "slowest_took", slowest.Setup+slowest.Warmup)
+	}
+	if err != nil {
+		s.log(slog.LevelError, "stage failed to setup", append(args, "error", err)...)
+		return
+	}
+	s.log(slog.LevelInfo, "stage setup", args...)
+}
@@ -0,0 +1,141 @@
+package orchestra
+
+import (
+	"fmt"
+	"time"
+)
+
+// State is where a player is in its life cycle
+type State int
+
+const (
+	StateAdded      State = iota // added to the stage, but not setup yet
+	StateSetup                   // setup successfully
+	StateWaiting                 // waiting to be released, see `orchestra.Latched`
+	StatePlaying                 // Play has been called, and hasn't returned yet
+	StateDone                    // Play returned a nil error
+	StateFailed                  // Setup or Play returned an error
+	StateCleaned                 // Clean has returned
+	StateRestarting              // Play returned an error, and the player is being restarted, see `orchestra.Restart`
+)
+
+func (s State) String() string {
+	switch s {
+	case StateAdded:
+		return "added"
+	case StateSetup:
+		return "setup"
+	case StateWaiting:
+		return "waiting"
+	case StatePlaying:
+		return "playing"
+	case StateDone:
+		return "done"
+	case StateFailed:
+		return "failed"
+	case StateCleaned:
+		return "cleaned"
+	case StateRestarting:
+		return "restarting"
+	}
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+// Phase is the part of the life cycle of a player
+type Phase int
+
+const (
+	PhaseSetup Phase = iota
+	PhasePlay
+	PhaseClean
+)
+
+func (p Phase) String() string {
+	switch p {
+	case PhaseSetup:
+		return "setup"
+	case PhasePlay:
+		return "play"
+	case PhaseClean:
+		return "clean"
+	}
+	return fmt.Sprintf("Phase(%d)", int(p))
+}
+
+// maxRecent is the number of errors a stage remembers
+const maxRecent = 16
+
+// record is an error returned by a player
+type record struct {
+	at     time.Time
+	player string
+	phase  Phase
+	err    error
+}
+
+// status is everything that changes about a player while the stage runs, it's guarded by (*Stage).mu
+type status struct {
+	state      State
+	setupTook  time.Duration
+	warmupTook time.Duration // how long the player took to warm up, see `orchestra.Warmer`
+	started    time.Time     // when Play was called
+	stopped    time.Time     // when Play returned
+	err        error         // the last error returned by the player
+	restarts   int           // the number of times the player has been restarted
+	stats      stats         // see (*Stage).Status
+}
+
+// transition moves the player to the given state, recording the error (if any) as it goes
+func (s *Stage) transition(e *entry, to State, phase Phase, err error) {
+	now := time.Now()
+	if err != nil && s.onError != nil {
+		defer s.onError(e.name, phase, err) // not under the lock, the handler may very well call Dump
+	}
+	event := Event{Time: now, Player: e.name, Group: e.group, State: to, Phase: phase, Err: err}
+	if s.journal != nil {
+		defer s.journal.write(event)
+	}
+	defer s.publish(event)
+	if err != nil {
+		for _, a := range s.alarms {
+			defer a.fail(record{at: now, player: e.name, phase: phase, err: err})
+		}
+	}
+	// the failures of a nested stage with a reporter have already been reported by the players that caused them
+	if err != nil && s.reporter != nil && !(e.nested != nil && e.nested.reporter != nil) {
+		s.mu.Lock()
+		f := s.failure(e, phase, err)
+		s.mu.Unlock()
+		defer s.reporter.Report(f)
+	}
+	var ended bool // whether a Play has just returned
+	var took time.Duration
+	if s.meter != nil {
+		defer func() {
+			if ended {
+				s.meterPlay(e, took, err)
+			}
+		}()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch to {
+	case StatePlaying:
+		e.status.started = now
+		e.status.stopped = time.Time{}
+	case StateDone, StateFailed, StateRestarting, StateWaiting:
+		if phase == PhasePlay && !e.status.started.IsZero() && e.status.stopped.IsZero() {
+			e.status.stopped = now
+			ended, took = true, now.Sub(e.status.started)
+			e.status.stats.played(took, err)
+		}
+	}
+	e.status.state = to
+	if err != nil {
+		e.status.err = err
+		s.recent = append(s.recent, record{at: now, player: e.name, phase: phase, err: err})
+		if len(s.recent) > maxRecent {
+			s.recent = s.recent[len(s.recent)-maxRecent:]
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package orchestra
+
+import (
+	"time"
+)
+
+// Meter is where a stage exports its metrics to, see `orchestra.Metrics`.
+// It's meant to be a thin adapter over a metrics library (like prometheus), the labels of every metric are
+// "player", "group" (see `orchestra.Group`), and "stage" (the path of the stage, see `orchestra.Failure`).
+// Note: the meter may be called concurrently from multiple players' goroutines
+type Meter interface {
+	Inc(name string, labels map[string]string)                // increments the named counter
+	Observe(name string, labels map[string]string, v float64) // records an observation in the named histogram
+}
+
+// The metrics exported by a stage, see `orchestra.Meter`
+const (
+	MetricPlays    = "orchestra_plays_total"           // a Play returned
+	MetricFailures = "orchestra_failures_total"        // a Play returned an error
+	MetricRestarts = "orchestra_restarts_total"        // a player was restarted, see `orchestra.Restart`
+	MetricPlayTime = "orchestra_play_duration_seconds" // how long a Play took
+)
+
+// Metrics makes the stage export the metrics of its players to m
+func Metrics(m Meter) StageOption {
+	return func(s *Stage) {
+		s.meter = m
+	}
+}
+
+// bounds are the upper bounds of the buckets of `orchestra.Histogram`, the last bucket has no upper bound
+var bounds = [...]time.Duration{
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+	10 * time.Second,
+	time.Minute,
+	10 * time.Minute,
+	time.Hour,
+}
+
+// Histogram is the distribution of the durations of the Plays of a player.
+// Counts[i] is the number of Plays that took at most Bounds()[i] (and more than the bound before it), and the last count is of the ones that took longer than all of the bounds
+type Histogram struct {
+	Counts [len(bounds) + 1]int
+}
+
+// Bounds returns the upper bounds of the buckets of the histogram, from 1ms to 1h
+func (h Histogram) Bounds() []time.Duration {
+	return bounds[:]
+}
+
+func (h *Histogram) observe(d time.Duration) {
+	for i, b := range bounds {
+		if d <= b {
+			h.Counts[i]++
+			return
+		}
+	}
+	h.Counts[len(bounds)]++
+}
+
+// PlayerStatus is a snapshot of a player, along with the statistics of its whole lifetime in the stage, see (*Stage).Status
+type PlayerStatus struct {
+	Player   string
+	Group    string // see `orchestra.Group`
+	State    State
+	Err      error // the last error returned by the player
+	Restarts int   // the number of times the player has been restarted
+
+	Plays     int           // the number of times its Play has returned
+	Failures  int           // the number of times its Play has returned an error
+	Uptime    time.Duration // the total time spent in Play, including the current one
+	Durations Histogram     // the distribution of how long its Plays took
+}
+
+// MTBF returns the mean time between failures of the player, i.e. its uptime per failure. It's zero if the player never failed
+func (p PlayerStatus) MTBF() time.Duration {
+	if p.Failures == 0 {
+		return 0
+	}
+	return p.Uptime / time.Duration(p.Failures)
+}
+
+// stats are the statistics of a player, it's guarded by (*Stage).mu
+type stats struct {
+	plays, failures int
+	uptime          time.Duration // the total time spent in the Plays that have returned
+	durations       Histogram
+}
+
+// played records a Play that took d, and returned err
+func (st *stats) played(d time.Duration, err error) {
+	st.plays++
+	if err != nil {
+		st.failures++
+	}
+	st.uptime += d
+	st.durations.observe(d)
+}
+
+// Status returns a snapshot of every player on the stage, in the order they were added
+func (s *Stage) Status() []PlayerStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	all := make([]PlayerStatus, 0, len(s.order))
+	for _, name := range s.order {
+		e := s.players[name]
+		st := e.status
+		p := PlayerStatus{
+			Player:    name,
+			Group:     e.group,
+			State:     st.state,
+			Err:       st.err,
+			Restarts:  st.restarts,
+			Plays:     st.stats.plays,
+			Failures:  st.stats.failures,
+			Uptime:    st.stats.uptime,
+			Durations: st.stats.durations,
+		}
+		if !st.started.IsZero() && st.stopped.IsZero() {
+			p.Uptime += now.Sub(st.started) // it's playing
+		}
+		all = append(all, p)
+	}
+	return all
+}
+
+// labels returns the labels of the metrics of the player
+func (s *Stage) labels(e *entry) map[string]string {
+	return map[string]string{"player": e.name, "group": e.group, "stage": s.path}
+}
+
+// meterPlay exports a Play that took d, and returned err
+func (s *Stage) meterPlay(e *entry, d time.Duration, err error) {
+	labels := s.labels(e)
+	s.meter.Inc(MetricPlays, labels)
+	if err != nil {
+		s.meter.Inc(MetricFailures, labels)
+	}
+	s.meter.Observe(MetricPlayTime, labels, d.Seconds())
+}
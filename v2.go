@@ -0,0 +1,214 @@
+package orchestra
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+)
+
+// PlayerV2 is a player with a single method, so the state set up for it doesn't need to be shared between a Setup, a Play, and a Clean.
+//
+//	func (s *server) Run(ctx context.Context, lc orchestra.Lifecycle) error {
+//		ln, err := net.Listen("tcp", s.addr)
+//		if err != nil {
+//			return err // it failed to setup
+//		}
+//		lc.Cleanup(func() { ln.Close() })
+//		lc.Ready() // it's setup, everything after this is its Play
+//		return serve(ctx, ln)
+//	}
+//
+// It is adapted into a `orchestra.Player` using `orchestra.FromV2`, and the other way around using `orchestra.ToV2`,
+// so both kinds of players can be added to the same stage.
+type PlayerV2 interface {
+	Run(ctx context.Context, lc Lifecycle) error
+}
+
+// Lifecycle is given to the Run of a `orchestra.PlayerV2`
+type Lifecycle interface {
+	Ready()               // marks the end of the setup of the player, and blocks until it is played, or cleaned without being played (the context given to Run is done then). An error returned by Run before Ready is a setup error
+	Cleanup(fn func())    // registers fn to be called when the player is cleaned, after Run has returned. They are called in the reverse order
+	Name() string         // the name the player was added to the stage with
+	Logger() *slog.Logger // the logger of the stage (see `orchestra.Logger`), or slog's default logger, with the name of the player attached
+	Fatal(err error)      // takes the process down, see `orchestra.Fatal`
+}
+
+// ErrNotReady is returned by the Setup of a `orchestra.PlayerV2` (see `orchestra.FromV2`) whose Run returned a nil error without calling (*Lifecycle).Ready
+var ErrNotReady = errors.New("ErrNotReady: Run returned without calling Ready")
+
+// lifecycle is the `orchestra.Lifecycle` given by `orchestra.FromV2`
+type lifecycle struct {
+	mu       sync.Mutex
+	ready    chan struct{}
+	once     sync.Once
+	playing  chan struct{}   // closed once the player is played
+	done     <-chan struct{} // closed once the context given to Run is done
+	cleanups []func()
+	name     string
+	logger   *slog.Logger
+	fatal    func(error)
+}
+
+func (lc *lifecycle) Ready() {
+	lc.once.Do(func() { close(lc.ready) })
+	select {
+	case <-lc.playing:
+	case <-lc.done:
+	}
+}
+
+func (lc *lifecycle) Cleanup(fn func()) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	lc.cleanups = append(lc.cleanups, fn)
+}
+
+func (lc *lifecycle) Name() string {
+	return lc.name
+}
+
+func (lc *lifecycle) Logger() *slog.Logger {
+	return lc.logger
+}
+
+func (lc *lifecycle) Fatal(err error) {
+	lc.fatal(err)
+}
+
+// runContext is the context given to Run, it carries the values of the context given to Play once the player is played,
+// so everything that's looked up in the context of a player, like `orchestra.ScratchDir`, works after (*Lifecycle).Ready
+type runContext struct {
+	context.Context
+	mu   sync.Mutex
+	play context.Context // the context given to Play, nil until it's called
+}
+
+func (c *runContext) Value(key any) any {
+	c.mu.Lock()
+	play := c.play
+	c.mu.Unlock()
+	if play != nil {
+		return play.Value(key)
+	}
+	return c.Context.Value(key)
+}
+
+// v2 is the player returned by `orchestra.FromV2`
+type v2 struct {
+	p     PlayerV2
+	stage *Stage // the stage the player is on, nil if it isn't on one
+	entry *entry
+
+	lc      *lifecycle
+	ctx     *runContext
+	cancel  context.CancelFunc
+	started sync.Once     // guards the closing of (*lifecycle).playing
+	done    chan struct{} // closed when Run returns
+	err     error         // the error returned by Run, valid after done is closed
+}
+
+// FromV2 adapts a `orchestra.PlayerV2` into a player.
+// Its Setup calls Run in the background, and returns once Run calls (*Lifecycle).Ready, or returns.
+// Its Play lets Run go past Ready, and waits for it to return, cancelling the context given to Run when the context given to Play is done.
+// So everything Run does after Ready is its Play, and is held back by the stage like any other Play, for eg, by `orchestra.Latched`.
+// Its Clean makes sure that Run has returned, and calls the functions registered with (*Lifecycle).Cleanup
+func FromV2(p PlayerV2) Player {
+	return &v2{p: p}
+}
+
+// bind tells the player which stage it's on, it is called by the stage before Setup
+func (v *v2) bind(s *Stage, it *entry) {
+	v.stage, v.entry = s, it
+}
+
+func (v *v2) Setup() error {
+	base, cancel := context.WithCancel(context.Background())
+	v.ctx, v.cancel, v.started = &runContext{Context: base}, cancel, sync.Once{}
+	v.lc = &lifecycle{ready: make(chan struct{}), playing: make(chan struct{}), done: base.Done(), logger: slog.Default()}
+	v.lc.fatal = func(err error) { Fatal(context.Background(), err) }
+	if v.stage != nil {
+		v.lc.name = v.entry.name
+		if v.stage.logger != nil {
+			v.lc.logger = v.stage.logger
+		}
+		v.lc.fatal = func(err error) { v.stage.fatal(v.entry, err) }
+	}
+	v.lc.logger = v.lc.logger.With("player", v.lc.name)
+	v.done = make(chan struct{})
+	go func() {
+		defer close(v.done)
+		v.err = v.p.Run(v.ctx, v.lc)
+	}()
+
+	select {
+	case <-v.lc.ready:
+		return nil
+	case <-v.done:
+	}
+	select {
+	case <-v.lc.ready:
+		return nil // it was ready after all, and it's done already
+	default:
+	}
+	v.cancel()
+	v.cleanup()
+	if v.err == nil {
+		return ErrNotReady
+	}
+	return v.err
+}
+
+func (v *v2) Play(ctx context.Context) error {
+	v.ctx.mu.Lock()
+	v.ctx.play = ctx
+	v.ctx.mu.Unlock()
+	v.started.Do(func() { close(v.lc.playing) })
+	defer context.AfterFunc(ctx, v.cancel)()
+	<-v.done
+	return v.err
+}
+
+func (v *v2) Clean() {
+	if v.done == nil {
+		return // it was never setup
+	}
+	v.cancel()
+	<-v.done
+	v.cleanup()
+}
+
+// cleanup calls the functions registered with (*Lifecycle).Cleanup, in the reverse order
+func (v *v2) cleanup() {
+	v.lc.mu.Lock()
+	fns := v.lc.cleanups
+	v.lc.cleanups = nil
+	v.lc.mu.Unlock()
+	for i := len(fns) - 1; i >= 0; i-- {
+		fns[i]()
+	}
+}
+
+// binder is implemented by players that need to know how they were added to the stage, see `orchestra.FromV2`
+type binder interface {
+	bind(s *Stage, it *entry)
+}
+
+// v1 is the `orchestra.PlayerV2` returned by `orchestra.ToV2`
+type v1 struct {
+	p Player
+}
+
+// ToV2 adapts a player into a `orchestra.PlayerV2`, its Run calls Setup, marks it ready, and calls Play, and Clean is registered as a cleanup
+func ToV2(p Player) PlayerV2 {
+	return v1{p: p}
+}
+
+func (v v1) Run(ctx context.Context, lc Lifecycle) error {
+	if err := v.p.Setup(); err != nil {
+		return err
+	}
+	lc.Cleanup(v.p.Clean)
+	lc.Ready()
+	return v.p.Play(ctx)
+}
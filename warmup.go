@@ -0,0 +1,101 @@
+package orchestra
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Warmer can be implemented by players that need to do some work after they're setup, but before they're considered ready,
+// for eg, priming caches, or precomputing lookup tables.
+// The stage calls Warmup right after the player's Setup returns successfully, so the players that depend on it are setup only after it has warmed up,
+// and the stage is ready (see (*Stage).Ready) only after every player has.
+// If Warmup returns an error, the player is cleaned, and the error is handled as if Setup had returned it.
+type Warmer interface {
+	Warmup(ctx context.Context) error
+}
+
+// ErrWarmup is returned when the Warmup of a player fails, see `orchestra.Warmer`
+type ErrWarmup struct {
+	Player string
+	Err    error
+}
+
+func (e ErrWarmup) Error() string {
+	return fmt.Sprintf("ErrWarmup: %s: %s", e.Player, e.Err)
+}
+
+func (e ErrWarmup) Unwrap() error {
+	return e.Err
+}
+
+// WarmupTimeout cancels the context given to the player's Warmup after d, see `orchestra.Warmer`.
+// If the player doesn't warm up in time, the error is reported as `ErrTimeout`.
+func WarmupTimeout(d time.Duration) Option {
+	return func(e *entry) {
+		e.warmupTimeout = d
+	}
+}
+
+// warmup warms the player up, if it's a warmer. It is called right after the player is setup, and cleans it if it fails to warm up
+func (s *Stage) warmup(it *entry) error {
+	w, ok := as[Warmer](it.player)
+	if !ok {
+		return nil
+	}
+	ctx := context.Background()
+	if it.warmupTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, it.warmupTimeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	defer func() {
+		s.mu.Lock()
+		it.status.warmupTook = time.Since(start)
+		s.mu.Unlock()
+	}()
+	err := s.warmupOnly(ctx, w)
+	if err == nil {
+		return nil
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		err = ErrTimeout{Timeout: it.warmupTimeout, Err: err}
+	}
+	cleanPlayer(context.Background(), it.player)
+	return ErrWarmup{Player: it.name, Err: err}
+}
+
+// warmupOnly calls the Warmup of the player, recovering it if the stage is configured to, so a panicking player is still cleaned
+func (s *Stage) warmupOnly(ctx context.Context, w Warmer) (err error) {
+	if s.recover {
+		defer recovered(&err)
+	}
+	return w.Warmup(ctx)
+}
+
+// Ready returns a channel that's closed once the stage has been setup successfully, and all of its players have warmed up (see `orchestra.Warmer`).
+// It's meant for readiness probes.
+func (s *Stage) Ready() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ready == nil {
+		s.ready = make(chan struct{})
+	}
+	return s.ready
+}
+
+// markReady closes the channel returned by (*Stage).Ready, if it isn't already
+func (s *Stage) markReady() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ready == nil {
+		s.ready = make(chan struct{})
+	}
+	select {
+	case <-s.ready:
+	default:
+		close(s.ready)
+	}
+}